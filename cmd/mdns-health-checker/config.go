@@ -0,0 +1,416 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+var (
+	labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// serviceInstanceRe matches fully qualified DNS-SD instance names, e.g. "Office Printer._ipp._tcp.local".
+	serviceInstanceRe = regexp.MustCompile(`(?i)^.+\._[^.]+\._(tcp|udp)(\.local)?\.?$`)
+	// serviceTypeRe matches DNS-SD service types, e.g. "_hap._tcp".
+	serviceTypeRe = regexp.MustCompile(`(?i)^_[^.]+\._(tcp|udp)(\.local)?\.?$`)
+)
+
+// Check types of the config file, matching usecase.CheckType.
+const (
+	checkTypeHost    = "host"
+	checkTypeService = "service"
+)
+
+// fileConfig is the layout of the file passed with --config.
+type fileConfig struct {
+	Hosts []fileHostConfig `yaml:"hosts"`
+}
+
+// fileHostConfig holds the per-host overrides. Zero values fall back to the flags.
+type fileHostConfig struct {
+	Name              string               `yaml:"name"`
+	Type              string               `yaml:"type"`
+	Timeout           time.Duration        `yaml:"timeout"`
+	Interval          time.Duration        `yaml:"interval"`
+	Attempts          int                  `yaml:"attempts"`
+	AttemptInterval   time.Duration        `yaml:"attempt_interval"`
+	AnswerWindow      time.Duration        `yaml:"answer_window"`
+	Retries           *int                 `yaml:"retries"`
+	FailThreshold     int                  `yaml:"fail_threshold"`
+	SuccessThreshold  int                  `yaml:"success_threshold"`
+	FlapThreshold     *int                 `yaml:"flap_threshold"`
+	FlapWindow        time.Duration        `yaml:"flap_window"`
+	Labels            map[string]string    `yaml:"labels"`
+	ExpectedAddresses []string             `yaml:"expected_addresses"`
+	RequireTXT        bool                 `yaml:"require_txt"`
+	TXT               []fileTXTExpectation `yaml:"txt"`
+}
+
+// fileTXTExpectation is an expectation on a TXT record key. Without value and match only the key has to be present.
+type fileTXTExpectation struct {
+	Key string `yaml:"key"`
+	// Value is the exact value the key must have.
+	Value *string `yaml:"value"`
+	// Match is a regular expression the whole value must match.
+	Match string `yaml:"match"`
+}
+
+// hostsConfig is the resolved set of hosts to check, merged from the config file and the flags.
+type hostsConfig struct {
+	Hosts []usecase.HostConfig
+	// Labels are the user-defined labels by host name. Every configured host has an entry.
+	Labels map[string]map[string]string
+	// DiscoveredInterval is the probe interval of discovered hosts, zero if discovery is disabled.
+	DiscoveredInterval time.Duration
+}
+
+func loadHostsConfig(s *Serve) (*hostsConfig, error) {
+	var file fileConfig
+
+	if s.Config != "" {
+		f, err := readFileConfig(s.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		file = *f
+	}
+
+	cfg := &hostsConfig{
+		Labels: make(map[string]map[string]string),
+	}
+
+	var (
+		errs []error
+		seen = make(map[string]struct{}, len(file.Hosts))
+	)
+
+	for i, fh := range file.Hosts {
+		host, err := resolveHostConfig(&s.Probe, fh)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hosts[%d]: %w", i, err))
+		}
+
+		if _, ok := seen[usecase.HostKey(host.Name)]; ok {
+			errs = append(errs, fmt.Errorf("hosts[%d]: duplicate host %q", i, host.Name))
+		}
+
+		seen[usecase.HostKey(host.Name)] = struct{}{}
+
+		if len(errs) > 0 {
+			continue
+		}
+
+		cfg.Hosts = append(cfg.Hosts, host)
+		cfg.Labels[host.Name] = fh.Labels
+	}
+
+	errs = append(errs, cfg.addFlagHosts(&s.Probe, seen, "--probe.hosts", s.Probe.Hosts, checkTypeHost)...)
+	errs = append(errs, cfg.addFlagHosts(&s.Probe, seen, "--probe.services", s.Probe.Services, checkTypeService)...)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if len(s.Discovery.Services) > 0 {
+		cfg.DiscoveredInterval = s.Probe.Interval
+	}
+
+	if len(cfg.Hosts) == 0 && cfg.DiscoveredInterval == 0 {
+		return nil, errors.New("no hosts to check, use --probe.hosts, --probe.services, --config or --discovery.services")
+	}
+
+	return cfg, nil
+}
+
+// addFlagHosts adds the hosts given with a flag, unless they are defined already. seen holds the keys of the defined
+// hosts and is extended with the added ones.
+func (c *hostsConfig) addFlagHosts(
+	p *Probe,
+	seen map[string]struct{},
+	flag string,
+	names []string,
+	typ string,
+) []error {
+	var errs []error
+
+	for _, name := range names {
+		if _, ok := seen[usecase.HostKey(name)]; ok {
+			continue
+		}
+
+		host, err := resolveHostConfig(p, fileHostConfig{Name: name, Type: typ})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", flag, err))
+			continue
+		}
+
+		seen[usecase.HostKey(host.Name)] = struct{}{}
+
+		c.Hosts = append(c.Hosts, host)
+		c.Labels[host.Name] = nil
+	}
+
+	return errs
+}
+
+// tickInterval returns the interval the worker has to tick at to honour every host interval.
+func (c *hostsConfig) tickInterval() time.Duration {
+	tick := c.DiscoveredInterval
+
+	for _, h := range c.Hosts {
+		if tick == 0 || h.Interval < tick {
+			tick = h.Interval
+		}
+	}
+
+	return tick
+}
+
+func readFileConfig(path string) (*fileConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+
+	defer f.Close()
+
+	var cfg fileConfig
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+func resolveHostConfig(p *Probe, fh fileHostConfig) (usecase.HostConfig, error) {
+	host := usecase.HostConfig{
+		Name:             strings.TrimSpace(fh.Name),
+		Query:            p.Query.policy(),
+		Interval:         p.Interval,
+		FailThreshold:    p.FailThreshold,
+		SuccessThreshold: p.SuccessThreshold,
+		FlapThreshold:    p.FlapThreshold,
+		FlapWindow:       p.FlapWindow,
+		RequireTXT:       fh.RequireTXT,
+	}
+
+	resolveQueryPolicy(&host.Query, fh)
+
+	if fh.Interval != 0 {
+		host.Interval = fh.Interval
+	}
+
+	if fh.FailThreshold != 0 {
+		host.FailThreshold = fh.FailThreshold
+	}
+
+	if fh.SuccessThreshold != 0 {
+		host.SuccessThreshold = fh.SuccessThreshold
+	}
+
+	if fh.FlapThreshold != nil {
+		host.FlapThreshold = *fh.FlapThreshold
+	}
+
+	if fh.FlapWindow != 0 {
+		host.FlapWindow = fh.FlapWindow
+	}
+
+	var errs []error
+
+	if host.Name == "" {
+		errs = append(errs, errors.New("name: must not be empty"))
+	}
+
+	switch fh.Type {
+	case "", checkTypeHost:
+		host.Type = usecase.CheckHost
+	case checkTypeService:
+		host.Type = usecase.CheckService
+	default:
+		errs = append(errs, fmt.Errorf("type: must be one of %s, %s", checkTypeHost, checkTypeService))
+	}
+
+	if host.Type == usecase.CheckService && !serviceInstanceRe.MatchString(host.Name) {
+		errs = append(errs, errors.New("name: must be a service instance name, e.g. 'My Printer._ipp._tcp.local'"))
+	}
+
+	if host.Type != usecase.CheckService && host.RequireTXT {
+		errs = append(errs, errors.New("require_txt: only applies to service checks"))
+	}
+
+	if host.Type != usecase.CheckService && len(fh.TXT) > 0 {
+		errs = append(errs, errors.New("txt: only applies to service checks"))
+	}
+
+	for i, ft := range fh.TXT {
+		expectation, err := resolveTXTExpectation(ft)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("txt[%d]: %w", i, err))
+			continue
+		}
+
+		host.TXT = append(host.TXT, expectation)
+	}
+
+	if fh.Retries != nil && *fh.Retries < 0 {
+		errs = append(errs, errors.New("retries: must not be negative"))
+	}
+
+	if fh.Retries != nil && fh.Attempts != 0 {
+		errs = append(errs, errors.New("retries: must not be set with attempts"))
+	}
+
+	errs = append(errs, validateQueryPolicy(host.Query)...)
+
+	if host.Interval <= host.Query.Timeout {
+		errs = append(errs, errors.New("interval: must be greater than timeout"))
+	}
+
+	if host.FailThreshold < 0 {
+		errs = append(errs, errors.New("fail_threshold: must not be negative"))
+	}
+
+	if host.SuccessThreshold < 0 {
+		errs = append(errs, errors.New("success_threshold: must not be negative"))
+	}
+
+	if host.FlapThreshold < 0 {
+		errs = append(errs, errors.New("flap_threshold: must not be negative"))
+	}
+
+	if host.FlapThreshold > 0 && host.FlapWindow <= 0 {
+		errs = append(errs, errors.New("flap_window: must be greater than zero"))
+	}
+
+	for name := range fh.Labels {
+		if !labelNameRe.MatchString(name) || strings.HasPrefix(name, "__") || name == "host" {
+			errs = append(errs, fmt.Errorf("labels: invalid label name %q", name))
+		}
+	}
+
+	for _, addr := range fh.ExpectedAddresses {
+		prefix, err := parsePrefixOrAddr(addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expected_addresses: %w", err))
+			continue
+		}
+
+		host.ExpectedAddrs = append(host.ExpectedAddrs, prefix)
+	}
+
+	if len(errs) > 0 {
+		return host, fmt.Errorf("%s: %w", host.Name, errors.Join(errs...))
+	}
+
+	return host, nil
+}
+
+// resolveQueryPolicy overrides the query policy of the flags with the one of the host. The deprecated retries are one
+// attempt less.
+func resolveQueryPolicy(policy *ports.QueryPolicy, fh fileHostConfig) {
+	if fh.Timeout != 0 {
+		policy.Timeout = fh.Timeout
+	}
+
+	switch {
+	case fh.Attempts != 0:
+		policy.Attempts = fh.Attempts
+	case fh.Retries != nil && *fh.Retries >= 0:
+		policy.Attempts = *fh.Retries + 1
+	}
+
+	if fh.AttemptInterval != 0 {
+		policy.AttemptInterval = fh.AttemptInterval
+	}
+
+	if fh.AnswerWindow != 0 {
+		policy.AnswerWindow = fh.AnswerWindow
+	}
+}
+
+func validateQueryPolicy(policy ports.QueryPolicy) []error {
+	var errs []error
+
+	if policy.Timeout <= 0 {
+		errs = append(errs, errors.New("timeout: must be greater than zero"))
+	}
+
+	if policy.Attempts <= 0 {
+		errs = append(errs, errors.New("attempts: must be greater than zero"))
+	}
+
+	if policy.AttemptInterval <= 0 {
+		errs = append(errs, errors.New("attempt_interval: must be greater than zero"))
+	}
+
+	if policy.AnswerWindow <= 0 {
+		errs = append(errs, errors.New("answer_window: must be greater than zero"))
+	}
+
+	if policy.Timeout > 0 && policy.AttemptInterval > 0 && !policy.AttemptsFit() {
+		errs = append(errs, errors.New("attempts: the last attempt must be sent within timeout"))
+	}
+
+	return errs
+}
+
+// resolveTXTExpectation compiles the expected value of a TXT record key into a regular expression matching the
+// whole value.
+func resolveTXTExpectation(ft fileTXTExpectation) (usecase.TXTExpectation, error) {
+	expectation := usecase.TXTExpectation{Key: strings.TrimSpace(ft.Key)}
+
+	if expectation.Key == "" {
+		return expectation, errors.New("key: must not be empty")
+	}
+
+	switch {
+	case ft.Value != nil && ft.Match != "":
+		return expectation, errors.New("value and match are mutually exclusive")
+	case ft.Value != nil:
+		expectation.Value = regexp.MustCompile("^(?:" + regexp.QuoteMeta(*ft.Value) + ")$")
+	case ft.Match != "":
+		re, err := regexp.Compile("^(?:" + ft.Match + ")$")
+		if err != nil {
+			return expectation, fmt.Errorf("match: %w", err)
+		}
+
+		expectation.Value = re
+	}
+
+	return expectation, nil
+}
+
+// parsePrefixOrAddr parses either a CIDR or a single IP address, the latter becoming a single-address prefix.
+func parsePrefixOrAddr(val string) (netip.Prefix, error) {
+	if strings.Contains(val, "/") {
+		prefix, err := netip.ParsePrefix(val)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", val, err)
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(val)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q: %w", val, err)
+	}
+
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+func TestLoadHostsConfig_MergesFileWithFlags(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+    timeout: 5s
+    interval: 1m
+    attempts: 2
+    fail_threshold: 3
+    flap_threshold: 4
+    flap_window: 15m
+    labels:
+      room: office
+    expected_addresses:
+      - 192.168.1.0/24
+      - fe80::1
+  - name: switch.local
+`)
+	s.Probe.Hosts = []string{"switch.local", "nas.local"}
+	s.Probe.FailThreshold, s.Probe.SuccessThreshold, s.Probe.FlapWindow = 1, 1, 10*time.Minute
+
+	cfg, err := loadHostsConfig(s)
+	require.NoError(t, err)
+
+	require.Equal(t, []usecase.HostConfig{
+		{
+			Name: "printer.local",
+			Query: ports.QueryPolicy{
+				Timeout:         5 * time.Second,
+				Attempts:        2,
+				AttemptInterval: time.Second,
+				AnswerWindow:    250 * time.Millisecond,
+			},
+			Interval:         time.Minute,
+			FailThreshold:    3,
+			SuccessThreshold: 1,
+			FlapThreshold:    4,
+			FlapWindow:       15 * time.Minute,
+			ExpectedAddrs: []netip.Prefix{
+				netip.MustParsePrefix("192.168.1.0/24"),
+				netip.MustParsePrefix("fe80::1/128"),
+			},
+		},
+		{
+			Name:             "switch.local",
+			Query:            testQueryPolicy,
+			Interval:         30 * time.Second,
+			FailThreshold:    1,
+			SuccessThreshold: 1,
+			FlapWindow:       10 * time.Minute,
+		},
+		{
+			Name:             "nas.local",
+			Query:            testQueryPolicy,
+			Interval:         30 * time.Second,
+			FailThreshold:    1,
+			SuccessThreshold: 1,
+			FlapWindow:       10 * time.Minute,
+		},
+	}, cfg.Hosts)
+
+	require.Equal(t, map[string]map[string]string{
+		"printer.local": {"room": "office"},
+		"switch.local":  nil,
+		"nas.local":     nil,
+	}, cfg.Labels)
+
+	require.Equal(t, 30*time.Second, cfg.tickInterval())
+}
+
+func TestLoadHostsConfig_SkipsDuplicateFlagHosts(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+    timeout: 5s
+`)
+	s.Probe.Hosts = []string{" printer.local", "Printer.local", "printer.local.", "printer", "nas.local", "NAS.local "}
+
+	cfg, err := loadHostsConfig(s)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(cfg.Hosts))
+	for _, h := range cfg.Hosts {
+		names = append(names, h.Name)
+	}
+
+	// Names differing only in case, surrounding spaces, the trailing dot or the .local domain are the same host, the
+	// config file one wins.
+	require.Equal(t, []string{"printer.local", "nas.local"}, names)
+	require.Equal(t, 5*time.Second, cfg.Hosts[0].Query.Timeout)
+}
+
+func TestLoadHostsConfig_RejectsInvalidHosts(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+    timeout: 1m
+    labels:
+      host: override
+    expected_addresses:
+      - not-an-ip
+  - name: printer.local
+  - name: switch.local
+    fail_threshold: -1
+    flap_threshold: 3
+  - name: nas.local
+    attempts: 5
+`)
+
+	_, err := loadHostsConfig(s)
+	require.ErrorContains(t, err, "interval: must be greater than timeout")
+	require.ErrorContains(t, err, "fail_threshold: must not be negative")
+	require.ErrorContains(t, err, "flap_window: must be greater than zero")
+	require.ErrorContains(t, err, "attempts: the last attempt must be sent within timeout")
+	require.ErrorContains(t, err, `invalid label name "host"`)
+	require.ErrorContains(t, err, `invalid IP address "not-an-ip"`)
+	require.ErrorContains(t, err, `duplicate host "printer.local"`)
+}
+
+func TestLoadHostsConfig_DeprecatedRetries(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+    retries: 1
+  - name: switch.local
+`)
+	retries := 0
+	s.Probe.Retries = &retries
+
+	cfg, err := loadHostsConfig(s)
+	require.NoError(t, err)
+
+	// The retries are the attempts after the first one.
+	require.Equal(t, 2, cfg.Hosts[0].Query.Attempts)
+	require.Equal(t, 1, cfg.Hosts[1].Query.Attempts)
+
+	s = newTestServe(t, `
+hosts:
+  - name: printer.local
+    retries: -1
+  - name: switch.local
+    retries: 1
+    attempts: 2
+`)
+
+	_, err = loadHostsConfig(s)
+	require.ErrorContains(t, err, "printer.local: retries: must not be negative")
+	require.ErrorContains(t, err, "switch.local: retries: must not be set with attempts")
+	require.NotContains(t, err.Error(), "attempts: must be greater than zero")
+}
+
+func TestLoadHostsConfig_ServiceChecks(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: Bridge._hap._tcp.local
+    type: service
+    require_txt: true
+`)
+	s.Probe.Services = []string{"Office Printer._ipp._tcp"}
+
+	cfg, err := loadHostsConfig(s)
+	require.NoError(t, err)
+
+	require.Equal(t, []usecase.HostConfig{
+		{
+			Name:       "Bridge._hap._tcp.local",
+			Type:       usecase.CheckService,
+			Query:      testQueryPolicy,
+			Interval:   30 * time.Second,
+			RequireTXT: true,
+		},
+		{
+			Name:     "Office Printer._ipp._tcp",
+			Type:     usecase.CheckService,
+			Query:    testQueryPolicy,
+			Interval: 30 * time.Second,
+		},
+	}, cfg.Hosts)
+}
+
+func TestLoadHostsConfig_RejectsInvalidServiceChecks(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+    type: service
+  - name: switch.local
+    require_txt: true
+  - name: nas.local
+    type: ping
+`)
+
+	_, err := loadHostsConfig(s)
+	require.ErrorContains(t, err, "printer.local: name: must be a service instance name")
+	require.ErrorContains(t, err, "switch.local: require_txt: only applies to service checks")
+	require.ErrorContains(t, err, "nas.local: type: must be one of host, service")
+}
+
+func TestLoadHostsConfig_TXTExpectations(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: Bridge._hap._tcp.local
+    type: service
+    txt:
+      - key: sf
+        value: "0"
+      - key: fv
+        match: '2\.\d+'
+      - key: id
+`)
+
+	cfg, err := loadHostsConfig(s)
+	require.NoError(t, err)
+	require.Len(t, cfg.Hosts, 1)
+
+	txt := cfg.Hosts[0].TXT
+	require.Len(t, txt, 3)
+
+	require.Equal(t, "sf", txt[0].Key)
+	require.True(t, txt[0].Value.MatchString("0"))
+	require.False(t, txt[0].Value.MatchString("01"))
+
+	require.Equal(t, "fv", txt[1].Key)
+	require.True(t, txt[1].Value.MatchString("2.14"))
+	require.False(t, txt[1].Value.MatchString("12.1"))
+
+	require.Equal(t, "id", txt[2].Key)
+	require.Nil(t, txt[2].Value)
+}
+
+func TestLoadHostsConfig_RejectsInvalidTXTExpectations(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: Bridge._hap._tcp.local
+    type: service
+    txt:
+      - value: "0"
+      - key: sf
+        value: "0"
+        match: "[01]"
+      - key: fv
+        match: "("
+  - name: switch.local
+    txt:
+      - key: sf
+`)
+
+	_, err := loadHostsConfig(s)
+	require.ErrorContains(t, err, "txt[0]: key: must not be empty")
+	require.ErrorContains(t, err, "txt[1]: value and match are mutually exclusive")
+	require.ErrorContains(t, err, "txt[2]: match: error parsing regexp")
+	require.ErrorContains(t, err, "switch.local: txt: only applies to service checks")
+}
+
+func TestLoadHostsConfig_RejectsUnknownFields(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+    timout: 5s
+`)
+
+	_, err := loadHostsConfig(s)
+	require.ErrorContains(t, err, "field timout not found")
+}
+
+var (
+	// testQuery are the query flags of the test commands, the default query policy of their hosts.
+	testQuery = Query{
+		Timeout:         10 * time.Second,
+		Attempts:        3,
+		AttemptInterval: time.Second,
+		AnswerWindow:    250 * time.Millisecond,
+	}
+	testQueryPolicy = testQuery.policy()
+)
+
+func newTestServe(t *testing.T, config string) *Serve {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+
+	return &Serve{
+		Config: path,
+		Probe: Probe{
+			Query:    testQuery,
+			Interval: 30 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/adapter/mdns"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+type DiscoverCmd struct {
+	Socket `embed:""`
+
+	Timeout  time.Duration `name:"timeout"   env:"DISCOVER_TIMEOUT" default:"3s"    help:"How long to collect answers for each browsed service type (e.g., 1s, 5s)."`
+	Output   string        `name:"output"                          default:"table" help:"Output format (table, json)."                                                enum:"table,json" short:"o"`
+	LogLevel string        `name:"log.level" env:"LOG_LEVEL"        default:"error" help:"Log level of the diagnostics written to stderr (debug, info, warn, error)"`
+	Services []string      `arg:""           name:"service"                         help:"DNS-SD service types to browse (e.g., '_ipp._tcp'). Browses every advertised type if omitted." optional:""`
+}
+
+func (c *DiscoverCmd) Run() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logLevel, err := parseLogLevel(c.LogLevel)
+	if err != nil {
+		return fmt.Errorf("failed to parse to log level: %w", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	client, err := c.Socket.newClient(logger)
+	if err != nil {
+		return fmt.Errorf("failed to create mdns client: %w", err)
+	}
+
+	defer func() { _ = client.Close() }()
+
+	instances, err := c.discover(ctx, mdns.NewBrowser(client))
+	if err != nil {
+		return err
+	}
+
+	if c.Output == "json" {
+		return writeInstancesJSON(os.Stdout, instances)
+	}
+
+	return writeInstancesTable(os.Stdout, instances)
+}
+
+func (c *DiscoverCmd) Validate() error {
+	var errs []error
+
+	if c.Timeout <= 0 {
+		errs = append(errs, errors.New("--timeout: must be greater than zero"))
+	}
+
+	errs = append(errs, c.Socket.validate("")...)
+
+	if !isLogLevel(c.LogLevel) {
+		errs = append(errs, errors.New("--log.level: must be one of debug, info, warn, error"))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// discover browses the requested service types, or every advertised one, concurrently.
+func (c *DiscoverCmd) discover(ctx context.Context, browser ports.ServiceBrowser) ([]ports.ServiceInstance, error) {
+	services := c.Services
+	if len(services) == 0 {
+		var err error
+
+		services, err = browser.ServiceTypes(ctx, c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enumerate service types: %w", err)
+		}
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		instances []ports.ServiceInstance
+		errs      []error
+	)
+
+	for _, service := range services {
+		wg.Go(func() {
+			found, err := browser.Browse(ctx, service, c.Timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to browse %s: %w", service, err))
+				return
+			}
+
+			instances = append(instances, found...)
+		})
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	slices.SortFunc(instances, func(a, b ports.ServiceInstance) int {
+		return strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name()))
+	})
+
+	return instances, nil
+}
+
+func writeInstancesTable(w io.Writer, instances []ports.ServiceInstance) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "SERVICE\tINSTANCE\tHOST\tPORT\tADDRESSES\tTXT")
+
+	for _, inst := range instances {
+		addrs := make([]string, 0, len(inst.Addrs))
+		for _, addr := range inst.Addrs {
+			addrs = append(addrs, addr.String())
+		}
+
+		port := ""
+		if inst.Host != "" {
+			port = strconv.Itoa(int(inst.Port))
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			inst.Service, inst.Instance, inst.Host, port, strings.Join(addrs, ","), strings.Join(inst.Text, " "))
+	}
+
+	return tw.Flush()
+}
+
+type discoverJSON struct {
+	Instances []instanceJSON `json:"instances"`
+}
+
+type instanceJSON struct {
+	Instance  string   `json:"instance"`
+	Service   string   `json:"service"`
+	Host      string   `json:"host"`
+	Port      uint16   `json:"port"`
+	Addresses []string `json:"addresses"`
+	TXT       []string `json:"txt"`
+}
+
+func writeInstancesJSON(w io.Writer, instances []ports.ServiceInstance) error {
+	out := discoverJSON{Instances: make([]instanceJSON, 0, len(instances))}
+
+	for _, inst := range instances {
+		item := instanceJSON{
+			Instance:  inst.Instance,
+			Service:   inst.Service,
+			Host:      inst.Host,
+			Port:      inst.Port,
+			Addresses: make([]string, 0, len(inst.Addrs)),
+			TXT:       inst.Text,
+		}
+
+		if item.TXT == nil {
+			item.TXT = []string{}
+		}
+
+		for _, addr := range inst.Addrs {
+			item.Addresses = append(item.Addresses, addr.String())
+		}
+
+		out.Instances = append(out.Instances, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	portsm "github.com/khmm12/mdns-health-checker/internal/ports/mocks"
+)
+
+func TestDiscoverCmd_BrowsesAdvertisedServiceTypes(t *testing.T) {
+	browser := portsm.NewMockServiceBrowser(t)
+
+	browser.On("ServiceTypes", mock.Anything, time.Second).Return([]string{"_ipp._tcp.local", "_hap._tcp.local"}, nil)
+	browser.On("Browse", mock.Anything, "_ipp._tcp.local", time.Second).Return([]ports.ServiceInstance{
+		{Instance: "Office Printer", Service: "_ipp._tcp.local", Host: "printer.local", Port: 631},
+	}, nil)
+	browser.On("Browse", mock.Anything, "_hap._tcp.local", time.Second).Return([]ports.ServiceInstance{
+		{Instance: "Bridge", Service: "_hap._tcp.local", Host: "bridge.local", Port: 51826},
+	}, nil)
+
+	cmd := &DiscoverCmd{Timeout: time.Second}
+
+	instances, err := cmd.discover(t.Context(), browser)
+	require.NoError(t, err)
+
+	require.Equal(t, []ports.ServiceInstance{
+		{Instance: "Bridge", Service: "_hap._tcp.local", Host: "bridge.local", Port: 51826},
+		{Instance: "Office Printer", Service: "_ipp._tcp.local", Host: "printer.local", Port: 631},
+	}, instances)
+}
+
+func TestDiscoverCmd_BrowsesGivenServiceTypes(t *testing.T) {
+	browser := portsm.NewMockServiceBrowser(t)
+
+	browser.On("Browse", mock.Anything, "_ipp._tcp", time.Second).Return([]ports.ServiceInstance{}, nil)
+
+	cmd := &DiscoverCmd{Timeout: time.Second, Services: []string{"_ipp._tcp"}}
+
+	instances, err := cmd.discover(t.Context(), browser)
+	require.NoError(t, err)
+	require.Empty(t, instances)
+}
+
+func TestWriteInstancesTable(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, writeInstancesTable(&buf, []ports.ServiceInstance{
+		{
+			Instance: "Office Printer",
+			Service:  "_ipp._tcp.local",
+			Host:     "printer.local",
+			Port:     631,
+			Text:     []string{"rp=ipp/print", "ty=Office"},
+			Addrs:    []netip.Addr{netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("fe80::1")},
+		},
+		{Instance: "Bridge", Service: "_hap._tcp.local"},
+	}))
+
+	require.Equal(t, ""+
+		"SERVICE          INSTANCE        HOST           PORT  ADDRESSES             TXT\n"+
+		"_ipp._tcp.local  Office Printer  printer.local  631   192.168.1.10,fe80::1  rp=ipp/print ty=Office\n"+
+		"_hap._tcp.local  Bridge                                                     \n", buf.String())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+type discoveryUC interface {
+	Execute(ctx context.Context, cmd usecase.DiscoverHostsCommand) ([]usecase.DiscoveredHost, error)
+}
+
+// discoveryTask periodically browses the discovery service types and hands the discovered hosts to the check task.
+type discoveryTask struct {
+	logger *slog.Logger
+	uc     discoveryUC
+	cmd    usecase.DiscoverHostsCommand
+	probe  *Probe
+	task   *task
+}
+
+func newDiscoveryTask(logger *slog.Logger, uc discoveryUC, d *Discovery, probe *Probe, task *task) *discoveryTask {
+	return &discoveryTask{
+		logger: logger,
+		uc:     uc,
+		cmd: usecase.DiscoverHostsCommand{
+			Services: d.Services,
+			Timeout:  d.Timeout,
+			Filter: usecase.HostFilter{
+				Include: lowerAll(d.Include),
+				Exclude: lowerAll(d.Exclude),
+			},
+			Expiry: d.Expiry,
+		},
+		probe: probe,
+		task:  task,
+	}
+}
+
+func (t *discoveryTask) Execute(ctx context.Context) error {
+	now := time.Now()
+
+	t.logger.InfoContext(ctx, "Run host discovery")
+
+	discovered, err := t.uc.Execute(ctx, t.cmd)
+	if err != nil {
+		t.logger.ErrorContext(ctx, "Failed to discover hosts", logging.Error(err))
+
+		// A partial result still refreshes the hosts which answered.
+		if discovered == nil {
+			return nil
+		}
+	}
+
+	hosts := make([]usecase.HostConfig, 0, len(discovered))
+
+	for _, d := range discovered {
+		host, err := resolveHostConfig(t.probe, fileHostConfig{Name: d.Name})
+		if err != nil {
+			t.logger.WarnContext(ctx, "Skipping discovered host", slog.String("host", d.Name), logging.Error(err))
+			continue
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	t.task.SetDiscoveredHosts(hosts)
+
+	t.logger.InfoContext(ctx, "Finished host discovery",
+		slog.Int("hosts", len(hosts)),
+		slog.Duration("duration", time.Since(now)),
+	)
+
+	return nil
+}
+
+// validate checks the discovery options. Discovery is disabled, and the options are ignored, without service types.
+func (d *Discovery) validate() []error {
+	if len(d.Services) == 0 {
+		return nil
+	}
+
+	var errs []error
+
+	if d.Timeout <= 0 {
+		errs = append(errs, errors.New("--discovery.timeout: must be greater than zero"))
+	}
+
+	if d.Interval <= d.Timeout {
+		errs = append(errs, errors.New("--discovery.interval: must be greater than --discovery.timeout"))
+	}
+
+	if d.Expiry < d.Interval {
+		errs = append(errs, errors.New("--discovery.expiry: must not be less than --discovery.interval"))
+	}
+
+	for _, service := range d.Services {
+		if !serviceTypeRe.MatchString(service) {
+			errs = append(errs, fmt.Errorf("--discovery.services: invalid service type %q, e.g. '_hap._tcp'", service))
+		}
+	}
+
+	errs = append(errs, validatePatterns("--discovery.include", d.Include)...)
+	errs = append(errs, validatePatterns("--discovery.exclude", d.Exclude)...)
+
+	return errs
+}
+
+func validatePatterns(flag string, patterns []string) []error {
+	var errs []error
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid pattern %q", flag, p))
+		}
+	}
+
+	return errs
+}
+
+func lowerAll(values []string) []string {
+	lower := make([]string, 0, len(values))
+	for _, v := range values {
+		lower = append(lower, strings.ToLower(v))
+	}
+
+	return lower
+}
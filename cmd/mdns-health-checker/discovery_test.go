@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+type stubDiscoveryUC struct {
+	hosts []usecase.DiscoveredHost
+	err   error
+}
+
+func (s *stubDiscoveryUC) Execute(context.Context, usecase.DiscoverHostsCommand) ([]usecase.DiscoveredHost, error) {
+	return s.hosts, s.err
+}
+
+func TestDiscoveryTask_ChecksDiscoveredHostsNotConfigured(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	probe := &Probe{Query: testQuery, Interval: 30 * time.Second}
+
+	configured := []usecase.HostConfig{{Name: "Plug-A.local.", Query: ports.QueryPolicy{Timeout: 5 * time.Second}}}
+	tk := newTask(logger, nil, configured)
+
+	uc := &stubDiscoveryUC{hosts: []usecase.DiscoveredHost{{Name: "plug-a.local"}, {Name: "plug-b.local"}}}
+	dt := newDiscoveryTask(logger, uc, &Discovery{Services: []string{"_hap._tcp"}}, probe, tk)
+
+	require.NoError(t, dt.Execute(t.Context()))
+	require.Equal(t, []usecase.HostConfig{
+		{Name: "Plug-A.local.", Query: ports.QueryPolicy{Timeout: 5 * time.Second}},
+		{Name: "plug-b.local", Query: testQueryPolicy, Interval: 30 * time.Second},
+	}, tk.checkedHosts())
+
+	// A failed discovery without any result keeps the discovered hosts.
+	uc.hosts, uc.err = nil, errors.New("socket closed")
+
+	require.NoError(t, dt.Execute(t.Context()))
+	require.Len(t, tk.checkedHosts(), 2)
+	require.Len(t, configured, 1)
+}
+
+func TestDiscovery_Validate(t *testing.T) {
+	d := &Discovery{
+		Services: []string{"_hap._tcp", "printer.local"},
+		Interval: time.Minute,
+		Timeout:  3 * time.Second,
+		Expiry:   30 * time.Second,
+		Include:  []string{"shelly-*"},
+		Exclude:  []string{"["},
+	}
+
+	err := errors.Join(d.validate()...)
+	require.ErrorContains(t, err, "--discovery.expiry: must not be less than --discovery.interval")
+	require.ErrorContains(t, err, `--discovery.services: invalid service type "printer.local"`)
+	require.ErrorContains(t, err, `--discovery.exclude: invalid pattern "["`)
+	require.NotContains(t, err.Error(), "--discovery.include")
+
+	require.Empty(t, (&Discovery{}).validate())
+}
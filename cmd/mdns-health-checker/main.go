@@ -4,21 +4,56 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strconv"
 
 	"github.com/alecthomas/kong"
 )
 
 type CLI struct {
-	Serve Serve `embed:""`
+	Serve    Serve       `cmd:"" default:"withargs" help:"Periodically probe mDNS hosts and export their status (default)."`
+	Probe    ProbeCmd    `cmd:"" name:"probe"       help:"Probe mDNS hosts once and exit with a Nagios-compatible status code."`
+	Discover DiscoverCmd `cmd:"" name:"discover"    help:"Browse DNS-SD services advertised on the network."`
+}
+
+// exitCodeError makes the process exit with the given code. Commands whose exit code carries the result
+// return it instead of calling os.Exit, so deferred cleanup still runs. err, if any, is printed before exiting.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+
+	return "exit status " + strconv.Itoa(e.code)
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
 }
 
 func main() {
 	var cli CLI
 
-	kong.Parse(&cli)
+	kctx := kong.Parse(&cli)
+
+	err := kctx.Run()
+
+	var exitErr *exitCodeError
+
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		if exitErr.err != nil {
+			kctx.Errorf("%s", exitErr.err)
+		}
 
-	err := serve(&cli)
-	if err != nil && errors.Is(err, context.Canceled) {
+		os.Exit(exitErr.code)
+	case errors.Is(err, context.Canceled):
 		os.Exit(1)
+	default:
+		kctx.FatalIfErrorf(err)
 	}
 }
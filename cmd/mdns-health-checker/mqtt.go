@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+
+	"github.com/khmm12/mdns-health-checker/internal/adapter/mqtt"
+)
+
+// mqttSchemes are the broker URL schemes supported by the MQTT client.
+var mqttSchemes = []string{"tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss"}
+
+// newPublisher connects to the broker and returns the client with the publisher of the host states, or nils if MQTT
+// is not enabled.
+func (m *MQTT) newPublisher(ctx context.Context, logger *slog.Logger) (*mqtt.Client, *mqtt.Publisher, error) {
+	if m.URL == "" {
+		return nil, nil, nil
+	}
+
+	client, err := mqtt.Connect(ctx, logger, mqtt.ClientOptions{
+		URL:         m.URL,
+		ClientID:    m.ClientID,
+		Username:    m.Username,
+		Password:    m.Password,
+		StatusTopic: mqtt.StatusTopic(m.TopicPrefix),
+		Timeout:     m.Timeout,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publisher := mqtt.NewPublisher(logger, client, mqtt.PublisherOptions{
+		TopicPrefix:     m.TopicPrefix,
+		DiscoveryPrefix: m.DiscoveryPrefix,
+	})
+
+	// A restarted broker may have lost the retained messages.
+	client.OnConnect(publisher.Resync)
+
+	return client, publisher, nil
+}
+
+func (m *MQTT) validate() []error {
+	if m.URL == "" {
+		return nil
+	}
+
+	var errs []error
+
+	if u, err := url.Parse(m.URL); err != nil || !slices.Contains(mqttSchemes, u.Scheme) || u.Host == "" {
+		errs = append(errs, errors.New("--mqtt.url: must be a tcp, mqtt, ssl, tls, mqtts, ws or wss URL"))
+	}
+
+	if m.ClientID == "" {
+		errs = append(errs, errors.New("--mqtt.client-id: must not be empty"))
+	}
+
+	if m.TopicPrefix == "" {
+		errs = append(errs, errors.New("--mqtt.topic-prefix: must not be empty"))
+	}
+
+	if m.Timeout <= 0 {
+		errs = append(errs, errors.New("--mqtt.timeout: must be greater than zero"))
+	}
+
+	return errs
+}
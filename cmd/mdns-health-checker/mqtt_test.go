@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMQTT_Validate(t *testing.T) {
+	m := &MQTT{
+		URL:         "http://broker:1883",
+		ClientID:    "mdns-health-checker",
+		TopicPrefix: "",
+		Timeout:     0,
+	}
+
+	err := errors.Join(m.validate()...)
+	require.ErrorContains(t, err, "--mqtt.url: must be a tcp, mqtt, ssl, tls, mqtts, ws or wss URL")
+	require.ErrorContains(t, err, "--mqtt.topic-prefix: must not be empty")
+	require.ErrorContains(t, err, "--mqtt.timeout: must be greater than zero")
+	require.NotContains(t, err.Error(), "--mqtt.client-id")
+
+	m.TopicPrefix, m.Timeout = "mdns-health-checker", time.Second
+
+	for _, scheme := range mqttSchemes {
+		m.URL = scheme + "://broker:1883"
+		require.Empty(t, m.validate(), scheme)
+	}
+
+	require.Empty(t, (&MQTT{}).validate(), "a disabled publisher is not validated")
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/adapter/mdns"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+type ProbeCmd struct {
+	Socket `embed:""`
+	Query  `embed:""`
+
+	Warning  time.Duration `name:"warning"                                   help:"Round-trip time above which an up host is reported as WARNING (disabled by default)."`
+	Critical time.Duration `name:"critical"                                  help:"Round-trip time above which an up host is reported as CRITICAL (disabled by default)."`
+	Output   string        `name:"output"                    default:"text"  help:"Output format (text, json)."                                             enum:"text,json" short:"o"`
+	LogLevel string        `name:"log.level" env:"LOG_LEVEL" default:"error" help:"Log level of the diagnostics written to stderr (debug, info, warn, error)"`
+	Services []string      `name:"service"                                   help:"DNS-SD service instance to check (e.g., 'My Printer._ipp._tcp.local'), can be repeated." sep:"none"`
+	Hosts    []string      `arg:""           name:"host"                     help:"mDNS hostnames to check (e.g., 'mydevice.local')."                                      optional:""`
+}
+
+// nagiosStatus is a Nagios plugin status, its value is the process exit code.
+type nagiosStatus int
+
+const (
+	nagiosOK nagiosStatus = iota
+	nagiosWarning
+	nagiosCritical
+	nagiosUnknown
+)
+
+func (s nagiosStatus) String() string {
+	switch s {
+	case nagiosOK:
+		return "OK"
+	case nagiosWarning:
+		return "WARNING"
+	case nagiosCritical:
+		return "CRITICAL"
+	case nagiosUnknown:
+		return "UNKNOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// severity orders the statuses the way Nagios aggregates them: a failed check outweighs an unknown one.
+func (s nagiosStatus) severity() int {
+	switch s {
+	case nagiosOK:
+		return 0
+	case nagiosUnknown:
+		return 1
+	case nagiosWarning:
+		return 2
+	case nagiosCritical:
+		return 3
+	default:
+		return 1
+	}
+}
+
+func (c *ProbeCmd) Run() error {
+	err := c.run()
+
+	// Nagios takes any exit code but the ones of the statuses for a broken plugin, so failures are UNKNOWN.
+	var exitErr *exitCodeError
+	if err != nil && !errors.As(err, &exitErr) {
+		return &exitCodeError{code: int(nagiosUnknown), err: err}
+	}
+
+	return err
+}
+
+func (c *ProbeCmd) run() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logLevel, err := parseLogLevel(c.LogLevel)
+	if err != nil {
+		return fmt.Errorf("failed to parse to log level: %w", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	results, err := c.probe(ctx, logger)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stdout, "MDNS %s - %s\n", nagiosUnknown, err)
+		return &exitCodeError{code: int(nagiosUnknown)}
+	}
+
+	report := newProbeReport(results, c.Warning, c.Critical)
+
+	if c.Output == "json" {
+		err = report.writeJSON(os.Stdout)
+	} else {
+		err = report.writeText(os.Stdout)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if report.status != nagiosOK {
+		return &exitCodeError{code: int(report.status)}
+	}
+
+	return nil
+}
+
+func (c *ProbeCmd) Validate() error {
+	var errs []error
+
+	if len(c.Hosts) == 0 && len(c.Services) == 0 {
+		errs = append(errs, errors.New("at least one host or --service must be given"))
+	}
+
+	for _, name := range c.Services {
+		if !serviceInstanceRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("--service: %q must be a service instance name, e.g. 'My Printer._ipp._tcp.local'",
+				name))
+		}
+	}
+
+	if c.Warning < 0 || c.Critical < 0 {
+		errs = append(errs, errors.New("--warning, --critical: must not be negative"))
+	}
+
+	if c.Warning > 0 && c.Critical > 0 && c.Warning > c.Critical {
+		errs = append(errs, errors.New("--warning: must not be greater than --critical"))
+	}
+
+	errs = append(errs, c.Socket.validate("")...)
+	errs = append(errs, c.Query.validate("")...)
+
+	if !isLogLevel(c.LogLevel) {
+		errs = append(errs, errors.New("--log.level: must be one of debug, info, warn, error"))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// probe runs a single check cycle over the hosts and services and returns their results in the order of the arguments.
+func (c *ProbeCmd) probe(ctx context.Context, logger *slog.Logger) ([]ports.ProbeResult, error) {
+	client, err := c.Socket.newClient(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mdns client: %w", err)
+	}
+
+	defer func() { _ = client.Close() }()
+
+	collector := &resultCollector{}
+	uc := usecase.NewCheckMDNSUseCase(
+		logger,
+		mdns.NewProbe(client),
+		mdns.NewServiceProbe(client),
+		collector,
+	)
+
+	hosts := make([]usecase.HostConfig, 0, len(c.Hosts)+len(c.Services))
+	for _, name := range c.Hosts {
+		hosts = append(hosts, usecase.HostConfig{Name: name, Query: c.Query.policy()})
+	}
+
+	for _, name := range c.Services {
+		hosts = append(hosts, usecase.HostConfig{
+			Name:  name,
+			Type:  usecase.CheckService,
+			Query: c.Query.policy(),
+		})
+	}
+
+	if err := uc.Execute(ctx, usecase.CheckMDNSCommand{Hosts: hosts}); err != nil {
+		return nil, fmt.Errorf("failed to probe hosts: %w", err)
+	}
+
+	return collector.results, nil
+}
+
+// resultCollector is a state publisher keeping the results of the last published cycle.
+type resultCollector struct {
+	mu      sync.Mutex
+	results []ports.ProbeResult
+}
+
+func (c *resultCollector) Publish(_ context.Context, results []ports.ProbeResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results = results
+
+	return nil
+}
+
+type probeReport struct {
+	status   nagiosStatus
+	hosts    []probeHostReport
+	warning  time.Duration
+	critical time.Duration
+}
+
+type probeHostReport struct {
+	result ports.ProbeResult
+	status nagiosStatus
+}
+
+func newProbeReport(results []ports.ProbeResult, warning, critical time.Duration) *probeReport {
+	r := &probeReport{warning: warning, critical: critical}
+
+	for _, res := range results {
+		status := hostNagiosStatus(res, warning, critical)
+		if status.severity() > r.status.severity() {
+			r.status = status
+		}
+
+		r.hosts = append(r.hosts, probeHostReport{result: res, status: status})
+	}
+
+	return r
+}
+
+func hostNagiosStatus(r ports.ProbeResult, warning, critical time.Duration) nagiosStatus {
+	switch r.State {
+	case ports.HostUp:
+		switch {
+		case critical > 0 && r.RTT > critical:
+			return nagiosCritical
+		case warning > 0 && r.RTT > warning:
+			return nagiosWarning
+		default:
+			return nagiosOK
+		}
+	case ports.HostDegraded, ports.HostFlapping:
+		return nagiosWarning
+	// Another device may be answering for the host, so its answer proves nothing.
+	case ports.HostMismatch, ports.HostDown:
+		return nagiosCritical
+	case ports.HostError, ports.HostUnknown:
+		return nagiosUnknown
+	default:
+		return nagiosUnknown
+	}
+}
+
+// writeText writes the report in the Nagios plugin output format: a summary line with performance data,
+// followed by one line per host.
+func (r *probeReport) writeText(w io.Writer) error {
+	var up int
+
+	perfdata := make([]string, 0, len(r.hosts))
+
+	for _, h := range r.hosts {
+		if h.result.State == ports.HostUp {
+			up++
+		}
+
+		perfdata = append(perfdata, r.perfdata(h.result))
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "MDNS %s - %d/%d hosts up | %s\n", r.status, up, len(r.hosts), strings.Join(perfdata, " "))
+
+	for _, h := range r.hosts {
+		fmt.Fprintf(&b, "%s: %s - %s", h.result.Host, h.status, h.result.State)
+
+		if h.result.State.Answered() {
+			fmt.Fprintf(&b, ", rtt %s, %s", h.result.RTT.Round(time.Microsecond), formatAddrs(h.result))
+		}
+
+		if h.result.Reason != "" {
+			fmt.Fprintf(&b, ", %s", h.result.Reason)
+		}
+
+		if h.result.Err != nil {
+			fmt.Fprintf(&b, ", %s", h.result.Err)
+		}
+
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func (r *probeReport) perfdata(res ports.ProbeResult) string {
+	value := "U"
+	if res.State.Answered() {
+		value = formatSeconds(res.RTT) + "s"
+	}
+
+	// A quote within the label is escaped by doubling it, e.g. a service instance "Bob's Printer".
+	label := strings.ReplaceAll(res.Host, "'", "''")
+
+	return fmt.Sprintf("'%s'=%s;%s;%s;0;", label, value, formatThreshold(r.warning), formatThreshold(r.critical))
+}
+
+type probeJSON struct {
+	Status   string          `json:"status"`
+	ExitCode int             `json:"exit_code"`
+	Hosts    []probeHostJSON `json:"hosts"`
+}
+
+type probeHostJSON struct {
+	Host       string   `json:"host"`
+	Status     string   `json:"status"`
+	State      string   `json:"state"`
+	RTTSeconds float64  `json:"rtt_seconds"`
+	Addresses  []string `json:"addresses"`
+	Family     string   `json:"family"`
+	Attempts   int      `json:"attempts,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+func (r *probeReport) writeJSON(w io.Writer) error {
+	out := probeJSON{
+		Status:   r.status.String(),
+		ExitCode: int(r.status),
+		Hosts:    make([]probeHostJSON, 0, len(r.hosts)),
+	}
+
+	for _, h := range r.hosts {
+		host := probeHostJSON{
+			Host:       h.result.Host,
+			Status:     h.status.String(),
+			State:      h.result.State.String(),
+			RTTSeconds: h.result.RTT.Seconds(),
+			Addresses:  make([]string, 0, len(h.result.Addrs)),
+			Family:     h.result.Family.String(),
+			Attempts:   h.result.Attempts,
+			Reason:     h.result.Reason,
+		}
+
+		for _, addr := range h.result.Addrs {
+			host.Addresses = append(host.Addresses, addr.String())
+		}
+
+		if h.result.Err != nil {
+			host.Error = h.result.Err.Error()
+		}
+
+		out.Hosts = append(out.Hosts, host)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
+
+func formatAddrs(r ports.ProbeResult) string {
+	addrs := make([]string, 0, len(r.Addrs))
+	for _, addr := range r.Addrs {
+		addrs = append(addrs, addr.String())
+	}
+
+	return strings.Join(addrs, ", ")
+}
+
+func formatThreshold(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+
+	return formatSeconds(d)
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func TestProbeCmd_ReportsFailuresAsUnknown(t *testing.T) {
+	err := (&ProbeCmd{LogLevel: "verbose", Hosts: []string{"printer.local"}}).Run()
+
+	var exitErr *exitCodeError
+
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, int(nagiosUnknown), exitErr.code)
+	require.ErrorContains(t, err, "failed to parse to log level")
+}
+
+func TestProbeReport_Status(t *testing.T) {
+	up := func(host string, rtt time.Duration) ports.ProbeResult {
+		return ports.ProbeResult{Host: host, State: ports.HostUp, RTT: rtt}
+	}
+
+	tests := []struct {
+		name    string
+		results []ports.ProbeResult
+		want    nagiosStatus
+	}{
+		{
+			name:    "all up",
+			results: []ports.ProbeResult{up("a.local", time.Millisecond), up("b.local", 2*time.Millisecond)},
+			want:    nagiosOK,
+		},
+		{
+			name:    "slow host",
+			results: []ports.ProbeResult{up("a.local", time.Millisecond), up("b.local", 150*time.Millisecond)},
+			want:    nagiosWarning,
+		},
+		{
+			name:    "very slow host",
+			results: []ports.ProbeResult{up("a.local", 600*time.Millisecond)},
+			want:    nagiosCritical,
+		},
+		{
+			name:    "errored host",
+			results: []ports.ProbeResult{up("a.local", time.Millisecond), {Host: "b.local", State: ports.HostError}},
+			want:    nagiosUnknown,
+		},
+		{
+			name: "degraded service",
+			results: []ports.ProbeResult{
+				up("a.local", time.Millisecond),
+				{Host: "b._hap._tcp.local", State: ports.HostDegraded, Reason: "txt_value_mismatch:sf"},
+			},
+			want: nagiosWarning,
+		},
+		{
+			name: "mismatched host",
+			results: []ports.ProbeResult{
+				up("a.local", time.Millisecond),
+				{Host: "b.local", State: ports.HostMismatch, Reason: "multiple_responders"},
+			},
+			want: nagiosCritical,
+		},
+		{
+			name: "down host outweighs errored host",
+			results: []ports.ProbeResult{
+				{Host: "a.local", State: ports.HostError},
+				{Host: "b.local", State: ports.HostDown},
+			},
+			want: nagiosCritical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newProbeReport(tt.results, 100*time.Millisecond, 500*time.Millisecond)
+			require.Equal(t, tt.want, report.status)
+		})
+	}
+}
+
+func TestProbeReport_WriteText(t *testing.T) {
+	report := newProbeReport([]ports.ProbeResult{
+		{
+			Host:   "printer.local",
+			State:  ports.HostUp,
+			RTT:    12 * time.Millisecond,
+			Addrs:  []netip.Addr{netip.MustParseAddr("192.168.1.10")},
+			Family: ports.FamilyIPv4,
+		},
+		{Host: "switch.local", State: ports.HostDown},
+		{Host: "nas.local", State: ports.HostError, Err: errors.New("socket closed")},
+		{
+			Host:  "Bob's Printer._ipp._tcp.local",
+			State: ports.HostUp,
+			RTT:   20 * time.Millisecond,
+			Addrs: []netip.Addr{netip.MustParseAddr("192.168.1.30")},
+		},
+	}, 0, 500*time.Millisecond)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.writeText(&buf))
+
+	require.Equal(t, "MDNS CRITICAL - 2/4 hosts up | "+
+		"'printer.local'=0.012s;;0.5;0; 'switch.local'=U;;0.5;0; 'nas.local'=U;;0.5;0; "+
+		"'Bob''s Printer._ipp._tcp.local'=0.02s;;0.5;0;\n"+
+		"printer.local: OK - up, rtt 12ms, 192.168.1.10\n"+
+		"switch.local: CRITICAL - down\n"+
+		"nas.local: UNKNOWN - error, socket closed\n"+
+		"Bob's Printer._ipp._tcp.local: OK - up, rtt 20ms, 192.168.1.30\n", buf.String())
+}
+
+func TestProbeReport_WriteJSON(t *testing.T) {
+	report := newProbeReport([]ports.ProbeResult{
+		{
+			Host:     "printer.local",
+			State:    ports.HostUp,
+			RTT:      250 * time.Millisecond,
+			Addrs:    []netip.Addr{netip.MustParseAddr("fe80::1")},
+			Family:   ports.FamilyIPv6,
+			Attempts: 2,
+		},
+	}, 100*time.Millisecond, 0)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.writeJSON(&buf))
+
+	require.JSONEq(t, `{
+		"status": "WARNING",
+		"exit_code": 1,
+		"hosts": [{
+			"host": "printer.local",
+			"status": "WARNING",
+			"state": "up",
+			"rtt_seconds": 0.25,
+			"addresses": ["fe80::1"],
+			"family": "ipv6",
+			"attempts": 2
+		}]
+	}`, buf.String())
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/khmm12/mdns-health-checker/internal/adapter/prometheus"
+)
+
+// newPusher returns the pusher of the exporter metrics, or nil if pushing is not enabled.
+func (p *Push) newPusher(logger *slog.Logger, exporter *prometheus.Exporter) *prometheus.Pusher {
+	if p.URL == "" {
+		return nil
+	}
+
+	return prometheus.NewPusher(logger, exporter, prometheus.PusherOptions{
+		URL:        p.URL,
+		Format:     prometheus.PushFormat(p.Format),
+		Job:        p.Job,
+		Grouping:   p.Grouping,
+		Username:   p.Username,
+		Password:   p.Password,
+		Timeout:    p.Timeout,
+		Backoff:    p.Backoff,
+		BufferSize: p.BufferSize,
+	})
+}
+
+func (p *Push) validate() []error {
+	if p.URL == "" {
+		return nil
+	}
+
+	var errs []error
+
+	if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.New("--push.url: must be an http or https URL"))
+	}
+
+	if p.Job == "" {
+		errs = append(errs, errors.New("--push.job: must not be empty"))
+	}
+
+	for name, value := range p.Grouping {
+		switch {
+		case !labelNameRe.MatchString(name) || strings.HasPrefix(name, "__") || name == "host":
+			errs = append(errs, fmt.Errorf("--push.grouping: %q is not a valid label name", name))
+		case name == "job":
+			errs = append(errs, errors.New("--push.grouping: the job label is set by --push.job"))
+		case value == "":
+			errs = append(errs, fmt.Errorf("--push.grouping: label %q must not be empty", name))
+		}
+	}
+
+	if p.Timeout <= 0 {
+		errs = append(errs, errors.New("--push.timeout: must be greater than zero"))
+	}
+
+	if p.Backoff <= 0 {
+		errs = append(errs, errors.New("--push.backoff: must be greater than zero"))
+	}
+
+	if p.BufferSize <= 0 {
+		errs = append(errs, errors.New("--push.buffer-size: must be greater than zero"))
+	}
+
+	return errs
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPush_Validate(t *testing.T) {
+	p := &Push{
+		URL:        "udp://pushgateway:9091",
+		Job:        "",
+		Grouping:   map[string]string{"instance": "attic", "job": "other", "__name__": "x", "host": "x", "site": ""},
+		Timeout:    time.Second,
+		Backoff:    0,
+		BufferSize: -1,
+	}
+
+	err := errors.Join(p.validate()...)
+	require.ErrorContains(t, err, "--push.url: must be an http or https URL")
+	require.ErrorContains(t, err, "--push.job: must not be empty")
+	require.ErrorContains(t, err, "--push.grouping: the job label is set by --push.job")
+	require.ErrorContains(t, err, `--push.grouping: "__name__" is not a valid label name`)
+	require.ErrorContains(t, err, `--push.grouping: "host" is not a valid label name`)
+	require.ErrorContains(t, err, `--push.grouping: label "site" must not be empty`)
+	require.ErrorContains(t, err, "--push.backoff: must be greater than zero")
+	require.ErrorContains(t, err, "--push.buffer-size: must be greater than zero")
+	require.NotContains(t, err.Error(), "instance")
+	require.NotContains(t, err.Error(), "--push.timeout")
+
+	require.Empty(t, (&Push{Job: ""}).validate(), "disabled pushing is not validated")
+}
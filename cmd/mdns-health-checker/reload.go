@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+// watchDebounce coalesces the bursts of events editors and config map updates produce for a single change.
+const watchDebounce = 500 * time.Millisecond
+
+// reloader re-reads the hosts configuration on SIGHUP or, optionally, whenever the config file changes.
+type reloader struct {
+	logger *slog.Logger
+	serve  *Serve
+	apply  func(cfg *hostsConfig)
+
+	mu      sync.Mutex
+	current *hostsConfig
+}
+
+func newReloader(logger *slog.Logger, serve *Serve, current *hostsConfig, apply func(cfg *hostsConfig)) *reloader {
+	return &reloader{
+		logger:  logger,
+		serve:   serve,
+		apply:   apply,
+		current: current,
+	}
+}
+
+func (r *reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	var changes <-chan struct{}
+
+	if r.serve.ConfigWatch {
+		ch, err := watchFile(ctx, r.logger, r.serve.Config)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to watch config file", logging.Error(err))
+		} else {
+			changes = ch
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.Reload(ctx, "signal")
+		case <-changes:
+			r.Reload(ctx, "file")
+		}
+	}
+}
+
+// Current returns the configuration currently in effect.
+func (r *reloader) Current() *hostsConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload loads the configuration and applies it if it differs from the current one.
+// An invalid configuration is logged and leaves the current one in place.
+func (r *reloader) Reload(ctx context.Context, trigger string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger := r.logger.With(slog.String("trigger", trigger))
+
+	cfg, err := loadHostsConfig(r.serve)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to reload configuration, keeping the current one", logging.Error(err))
+		return
+	}
+
+	diff := diffHostsConfig(r.current, cfg)
+	if diff.empty() && cfg.tickInterval() == r.current.tickInterval() {
+		logger.InfoContext(ctx, "Configuration unchanged")
+		return
+	}
+
+	for _, host := range diff.added {
+		logger.InfoContext(ctx, "Host added", slog.String("host", host))
+	}
+
+	for _, host := range diff.removed {
+		logger.InfoContext(ctx, "Host removed", slog.String("host", host))
+	}
+
+	for _, host := range slices.Sorted(maps.Keys(diff.changed)) {
+		logger.InfoContext(ctx, "Host changed", slog.String("host", host), slog.Any("changes", diff.changed[host]))
+	}
+
+	r.apply(cfg)
+	r.current = cfg
+
+	logger.InfoContext(ctx, "Reloaded configuration",
+		slog.Int("hosts", len(cfg.Hosts)),
+		slog.Duration("interval", cfg.tickInterval()),
+	)
+}
+
+type hostsDiff struct {
+	added   []string
+	removed []string
+	// changed holds a human-readable list of changes by host name.
+	changed map[string][]string
+}
+
+func (d hostsDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+func diffHostsConfig(prev, next *hostsConfig) hostsDiff {
+	diff := hostsDiff{changed: make(map[string][]string)}
+
+	prevHosts := make(map[string]usecase.HostConfig, len(prev.Hosts))
+	for _, h := range prev.Hosts {
+		prevHosts[h.Name] = h
+	}
+
+	nextHosts := make(map[string]struct{}, len(next.Hosts))
+
+	for _, h := range next.Hosts {
+		nextHosts[h.Name] = struct{}{}
+
+		old, ok := prevHosts[h.Name]
+		if !ok {
+			diff.added = append(diff.added, h.Name)
+			continue
+		}
+
+		if changes := diffHost(old, h, prev.Labels[h.Name], next.Labels[h.Name]); len(changes) > 0 {
+			diff.changed[h.Name] = changes
+		}
+	}
+
+	for _, h := range prev.Hosts {
+		if _, ok := nextHosts[h.Name]; !ok {
+			diff.removed = append(diff.removed, h.Name)
+		}
+	}
+
+	return diff
+}
+
+func diffHost(prev, next usecase.HostConfig, prevLabels, nextLabels map[string]string) []string {
+	var changes []string
+
+	if prev.Type != next.Type {
+		changes = append(changes, fmt.Sprintf("type: %s -> %s", prev.Type, next.Type))
+	}
+
+	changes = append(changes, diffQuery(prev.Query, next.Query)...)
+
+	if prev.Interval != next.Interval {
+		changes = append(changes, fmt.Sprintf("interval: %s -> %s", prev.Interval, next.Interval))
+	}
+
+	if prev.FailThreshold != next.FailThreshold {
+		changes = append(changes, fmt.Sprintf("fail_threshold: %d -> %d", prev.FailThreshold, next.FailThreshold))
+	}
+
+	if prev.SuccessThreshold != next.SuccessThreshold {
+		changes = append(changes,
+			fmt.Sprintf("success_threshold: %d -> %d", prev.SuccessThreshold, next.SuccessThreshold))
+	}
+
+	if prev.FlapThreshold != next.FlapThreshold {
+		changes = append(changes, fmt.Sprintf("flap_threshold: %d -> %d", prev.FlapThreshold, next.FlapThreshold))
+	}
+
+	if prev.FlapWindow != next.FlapWindow {
+		changes = append(changes, fmt.Sprintf("flap_window: %s -> %s", prev.FlapWindow, next.FlapWindow))
+	}
+
+	if !slices.Equal(prev.ExpectedAddrs, next.ExpectedAddrs) {
+		changes = append(changes, fmt.Sprintf("expected_addresses: %v -> %v", prev.ExpectedAddrs, next.ExpectedAddrs))
+	}
+
+	if prev.RequireTXT != next.RequireTXT {
+		changes = append(changes, fmt.Sprintf("require_txt: %t -> %t", prev.RequireTXT, next.RequireTXT))
+	}
+
+	if prevTXT, nextTXT := formatTXTExpectations(prev.TXT), formatTXTExpectations(next.TXT); prevTXT != nextTXT {
+		changes = append(changes, fmt.Sprintf("txt: %s -> %s", prevTXT, nextTXT))
+	}
+
+	if !maps.Equal(prevLabels, nextLabels) {
+		changes = append(changes, fmt.Sprintf("labels: %v -> %v", prevLabels, nextLabels))
+	}
+
+	return changes
+}
+
+func diffQuery(prev, next ports.QueryPolicy) []string {
+	var changes []string
+
+	if prev.Timeout != next.Timeout {
+		changes = append(changes, fmt.Sprintf("timeout: %s -> %s", prev.Timeout, next.Timeout))
+	}
+
+	if prev.Attempts != next.Attempts {
+		changes = append(changes, fmt.Sprintf("attempts: %d -> %d", prev.Attempts, next.Attempts))
+	}
+
+	if prev.AttemptInterval != next.AttemptInterval {
+		changes = append(changes,
+			fmt.Sprintf("attempt_interval: %s -> %s", prev.AttemptInterval, next.AttemptInterval))
+	}
+
+	if prev.AnswerWindow != next.AnswerWindow {
+		changes = append(changes, fmt.Sprintf("answer_window: %s -> %s", prev.AnswerWindow, next.AnswerWindow))
+	}
+
+	return changes
+}
+
+// formatTXTExpectations returns a comparable representation of TXT expectations.
+func formatTXTExpectations(expectations []usecase.TXTExpectation) string {
+	parts := make([]string, 0, len(expectations))
+
+	for _, e := range expectations {
+		if e.Value == nil {
+			parts = append(parts, e.Key)
+			continue
+		}
+
+		parts = append(parts, fmt.Sprintf("%s=%s", e.Key, e.Value))
+	}
+
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// watchFile notifies about changes of the file at path.
+//
+// The parent directory is watched rather than the file itself, so changes made by replacing the file
+// (editors saving atomically, Kubernetes config maps swapping symlinks) are not lost.
+func watchFile(ctx context.Context, logger *slog.Logger, path string) (<-chan struct{}, error) {
+	path = filepath.Clean(path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer watcher.Close()
+
+		debounce := time.NewTimer(watchDebounce)
+		debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) == path || filepath.Base(event.Name) == "..data" {
+					debounce.Reset(watchDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				logger.WarnContext(ctx, "Config file watcher error", logging.Error(err))
+			case <-debounce.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
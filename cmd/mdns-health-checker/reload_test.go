@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader_AppliesChangedConfiguration(t *testing.T) {
+	s := newTestServe(t, `
+hosts:
+  - name: printer.local
+  - name: switch.local
+`)
+
+	current, err := loadHostsConfig(s)
+	require.NoError(t, err)
+
+	var applied []*hostsConfig
+
+	r := newReloader(slog.New(slog.NewTextHandler(io.Discard, nil)), s, current, func(cfg *hostsConfig) {
+		applied = append(applied, cfg)
+	})
+
+	r.Reload(t.Context(), "test")
+	require.Empty(t, applied)
+
+	require.NoError(t, os.WriteFile(s.Config, []byte(`
+hosts:
+  - name: printer.local
+    timeout: 5s
+  - name: nas.local
+`), 0o600))
+
+	r.Reload(t.Context(), "test")
+	require.Len(t, applied, 1)
+
+	diff := diffHostsConfig(current, applied[0])
+	require.Equal(t, []string{"nas.local"}, diff.added)
+	require.Equal(t, []string{"switch.local"}, diff.removed)
+	require.Equal(t, map[string][]string{"printer.local": {"timeout: 10s -> 5s"}}, diff.changed)
+
+	require.NoError(t, os.WriteFile(s.Config, []byte(`hosts: [{name: printer.local, timeout: 1m}]`), 0o600))
+
+	r.Reload(t.Context(), "test")
+	require.Len(t, applied, 1)
+	require.Equal(t, 5*time.Second, r.current.Hosts[0].Query.Timeout)
+}
@@ -7,46 +7,133 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/khmm12/mdns-health-checker/internal/adapter/httpsrv"
 	"github.com/khmm12/mdns-health-checker/internal/adapter/mdns"
+	"github.com/khmm12/mdns-health-checker/internal/adapter/memstore"
 	"github.com/khmm12/mdns-health-checker/internal/adapter/prometheus"
 	"github.com/khmm12/mdns-health-checker/internal/adapter/worker"
 	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+	"github.com/khmm12/mdns-health-checker/internal/common/tracing"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
 	"github.com/khmm12/mdns-health-checker/internal/usecase"
 )
 
+// Socket holds the options of the multicast sockets mDNS queries are sent over.
+type Socket struct {
+	Concurrency int    `name:"concurrency" env:"PROBE_CONCURRENCY" default:"10"             help:"The maximum number of mDNS probes to run concurrently."`
+	UseIPv4     bool   `name:"ipv4"        env:"PROBE_USE_IPV4"    default:"true"           help:"Enable mDNS probing over IPv4. Enabled by default."`
+	IPv4Addr    string `name:"ipv4.addr"   env:"PROBE_IPV4_ADDR"   default:"224.0.0.0:5353" help:"IPv4 address to bind to for mDNS probing."`
+	UseIPv6     bool   `name:"ipv6"        env:"PROBE_USE_IPV6"    default:"true"           help:"Enable mDNS probing over IPv6. Enabled by default."`
+	IPv6Addr    string `name:"ipv6.addr"   env:"PROBE_IPV6_ADDR"   default:"[FF02::]:5353"  help:"IPv6 address to bind to for mDNS probing."`
+}
+
+// Query holds the query policy of the probes, the default of every host.
+type Query struct {
+	Timeout         time.Duration `name:"timeout"          env:"PROBE_TIMEOUT"          default:"10s"   help:"The maximum duration to wait for an mDNS probe response from a single host, including every attempt (e.g., 1s, 5m, 1h)."`
+	Attempts        int           `name:"attempts"         env:"PROBE_ATTEMPTS"         default:"3"     help:"The maximum number of queries sent to a host or service within the probe timeout."`
+	AttemptInterval time.Duration `name:"attempt-interval" env:"PROBE_ATTEMPT_INTERVAL" default:"1s"    help:"The time before a host or service that did not answer is queried again, doubled after every query (e.g., 1s, 2s, 4s)."`
+	AnswerWindow    time.Duration `name:"answer-window"    env:"PROBE_ANSWER_WINDOW"    default:"250ms" help:"How long answers from other responders are collected after the first answer to a host probe, to detect name conflicts."`
+	Retries         *int          `name:"retries"          env:"PROBE_RETRIES"                          help:"Deprecated, use the attempts instead. The number of queries sent after the first one, overrides the attempts."`
+}
+
 type Probe struct {
-	Interval    time.Duration `name:"interval"    env:"PROBE_INTERVAL"    default:"30s"            help:"The interval between each full cycle of mDNS host checks (e.g., 1s, 5m, 1h)."`
-	Timeout     time.Duration `name:"timeout"     env:"PROBE_TIMEOUT"     default:"10s"            help:"The maximum duration to wait for an mDNS probe response from a single host (e.g., 1s, 5m, 1h)."`
-	Concurrency int           `name:"concurrency" env:"PROBE_CONCURRENCY" default:"10"             help:"The maximum number of mDNS probes to run concurrently."`
-	UseIPv4     bool          `name:"ipv4"        env:"PROBE_USE_IPV4"    default:"true"           help:"Enable mDNS probing over IPv4. Enabled by default."`
-	IPv4Addr    string        `name:"ipv4.addr"   env:"PROBE_IPV4_ADDR"   default:"224.0.0.0:5353" help:"IPv4 address to bind to for mDNS probing."`
-	UseIPv6     bool          `name:"ipv6"        env:"PROBE_USE_IPV6"    default:"true"           help:"Enable mDNS probing over IPv6. Enabled by default."`
-	IPv6Addr    string        `name:"ipv6.addr"   env:"PROBE_IPV6_ADDR"   default:"[FF02::]:5353"  help:"IPv6 address to bind to for mDNS probing."`
-	Hosts       []string      `name:"hosts"       env:"PROBE_HOSTS"                                help:"A comma-separated list of mDNS hostnames (e.g., 'mydevice.local,another.local') to check."      required:"" sep:","`
+	Socket `embed:""`
+	Query  `embed:""`
+
+	Interval         time.Duration `name:"interval"          env:"PROBE_INTERVAL"          default:"30s"    help:"The interval between each full cycle of mDNS host checks (e.g., 1s, 5m, 1h)."`
+	FailThreshold    int           `name:"fail-threshold"    env:"PROBE_FAIL_THRESHOLD"    default:"1"      help:"The number of consecutive failed probes before an up host is reported down."`
+	SuccessThreshold int           `name:"success-threshold" env:"PROBE_SUCCESS_THRESHOLD" default:"1"      help:"The number of consecutive successful probes before a host is reported up again."`
+	FlapThreshold    int           `name:"flap-threshold"    env:"PROBE_FLAP_THRESHOLD"    default:"0"      help:"The number of changes between up and not up within the flap window from which a host is reported flapping. Zero disables flap detection."`
+	FlapWindow       time.Duration `name:"flap-window"       env:"PROBE_FLAP_WINDOW"       default:"10m"    help:"The time window in which the changes of a host are counted for flap detection."`
+	Hosts            []string      `name:"hosts"             env:"PROBE_HOSTS"                              help:"A comma-separated list of mDNS hostnames (e.g., 'mydevice.local,another.local') to check." sep:","`
+	Services         []string      `name:"services"          env:"PROBE_SERVICES"                           help:"A comma-separated list of DNS-SD service instances (e.g., 'My Printer._ipp._tcp.local') to check." sep:","`
+	Mode             string        `name:"mode"              env:"PROBE_MODE"              default:"active" help:"How hosts are checked: active sends queries, passive only listens to the announcements on the network (active, passive)." enum:"active,passive"`
+}
+
+// probeModePassive checks the hosts by listening to the responses on the network instead of sending queries.
+const probeModePassive = "passive"
+
+// Discovery holds the options of the automatic discovery of hosts to check.
+type Discovery struct {
+	Services []string      `name:"services" env:"DISCOVERY_SERVICES"                help:"A comma-separated list of DNS-SD service types (e.g., '_hap._tcp,_shelly._tcp') to browse for hosts to check. Enables host discovery." sep:","`
+	Interval time.Duration `name:"interval" env:"DISCOVERY_INTERVAL" default:"5m"  help:"The interval between browses of the discovery service types."`
+	Timeout  time.Duration `name:"timeout"  env:"DISCOVERY_TIMEOUT"  default:"3s"  help:"How long answers are collected for each discovery service type."`
+	Expiry   time.Duration `name:"expiry"   env:"DISCOVERY_EXPIRY"   default:"30m" help:"How long a discovered host is checked after it was last seen."`
+	Include  []string      `name:"include"  env:"DISCOVERY_INCLUDE"                help:"A comma-separated list of glob patterns (e.g., 'shelly-*.local') of discovered host names to check. Empty includes every host." sep:","`
+	Exclude  []string      `name:"exclude"  env:"DISCOVERY_EXCLUDE"                help:"A comma-separated list of glob patterns of discovered host names to skip, taking precedence over --discovery.include." sep:","`
+}
+
+// Webhook holds the options of the webhook notified of every host state change.
+type Webhook struct {
+	URL       string            `name:"url"        env:"WEBHOOK_URL"                      help:"The http(s) URL every host state change is posted to. Enables the webhook."`
+	Template  string            `name:"template"   env:"WEBHOOK_TEMPLATE"                 help:"Path to a Go template rendering the request body from the state change. Defaults to a JSON document." type:"existingfile"`
+	Headers   map[string]string `name:"headers"    env:"WEBHOOK_HEADERS"                  help:"Headers added to every request (e.g., 'Authorization=Bearer token;X-Source=mdns')."`
+	Secret    string            `name:"secret"     env:"WEBHOOK_SECRET"                   help:"Secret signing every request body with HMAC-SHA256 in the X-Signature-256 header."`
+	Timeout   time.Duration     `name:"timeout"    env:"WEBHOOK_TIMEOUT"    default:"10s" help:"The maximum duration of a single webhook request."`
+	Retries   int               `name:"retries"    env:"WEBHOOK_RETRIES"    default:"3"   help:"The number of additional requests sent for a state change whose delivery failed."`
+	Backoff   time.Duration     `name:"backoff"    env:"WEBHOOK_BACKOFF"    default:"1s"  help:"The delay before the first retry of a failed delivery, doubled after every retry."`
+	QueueSize int               `name:"queue-size" env:"WEBHOOK_QUEUE_SIZE" default:"100" help:"The number of state changes waiting for delivery, further changes are dropped."`
+}
+
+// MQTT holds the options of the MQTT broker the host states are published to.
+type MQTT struct {
+	URL             string        `name:"url"              env:"MQTT_URL"                                            help:"The broker the host states are published to (e.g., 'tcp://broker:1883', 'ssl://broker:8883'). Enables MQTT."`
+	ClientID        string        `name:"client-id"        env:"MQTT_CLIENT_ID"        default:"mdns-health-checker" help:"The client ID of the connection to the broker."`
+	Username        string        `name:"username"         env:"MQTT_USERNAME"                                       help:"The username of the connection to the broker."`
+	Password        string        `name:"password"         env:"MQTT_PASSWORD"                                       help:"The password of the connection to the broker."`
+	TopicPrefix     string        `name:"topic-prefix"     env:"MQTT_TOPIC_PREFIX"     default:"mdns-health-checker" help:"The prefix of the topics the host states are published to."`
+	DiscoveryPrefix string        `name:"discovery-prefix" env:"MQTT_DISCOVERY_PREFIX" default:"homeassistant"       help:"The Home Assistant discovery prefix the hosts are announced with. Empty disables the discovery."`
+	Timeout         time.Duration `name:"timeout"          env:"MQTT_TIMEOUT"          default:"10s"                 help:"The maximum duration of connecting to the broker and of a single publication."`
+}
+
+// Push holds the options of the endpoint the metrics are pushed to after every check cycle.
+type Push struct {
+	URL        string            `name:"url"         env:"PUSH_URL"                                       help:"The Pushgateway or remote-write URL the metrics are pushed to after every check cycle (e.g., 'http://pushgateway:9091', 'http://prometheus:9090/api/v1/write'). Enables pushing."`
+	Format     string            `name:"format"      env:"PUSH_FORMAT"      default:"pushgateway"         help:"The protocol of the push endpoint (pushgateway, remote-write)." enum:"pushgateway,remote-write"`
+	Job        string            `name:"job"         env:"PUSH_JOB"         default:"mdns-health-checker" help:"The job label of the pushed metrics."`
+	Grouping   map[string]string `name:"grouping"    env:"PUSH_GROUPING"                                  help:"Labels identifying the checker in addition to the job (e.g., 'instance=attic;site=home'). Remote write adds them to every series."`
+	Username   string            `name:"username"    env:"PUSH_USERNAME"                                  help:"The basic auth username of the push endpoint."`
+	Password   string            `name:"password"    env:"PUSH_PASSWORD"                                  help:"The basic auth password of the push endpoint."`
+	Timeout    time.Duration     `name:"timeout"     env:"PUSH_TIMEOUT"     default:"10s"                 help:"The maximum duration of a single push."`
+	Backoff    time.Duration     `name:"backoff"     env:"PUSH_BACKOFF"     default:"1s"                  help:"The delay before the first retry of a failed push, doubled after every retry up to a minute."`
+	BufferSize int               `name:"buffer-size" env:"PUSH_BUFFER_SIZE" default:"100"                 help:"The number of remote-write snapshots kept while the endpoint is unreachable, the oldest ones are dropped first."`
 }
 
 type Metrics struct {
-	Addr string `name:"addr" env:"METRICS_ADDR" default:"0.0.0.0:8080" help:"HTTP Address to bind Prometheus metrics"`
-	Path string `name:"path" env:"METRICS_PATH" default:"/metrics"     help:"Path to serve Prometheus metrics"`
+	Addr             string `name:"addr"              env:"METRICS_ADDR"              default:"0.0.0.0:8080" help:"HTTP Address to bind Prometheus metrics"`
+	Path             string `name:"path"              env:"METRICS_PATH"              default:"/metrics"     help:"Path to serve Prometheus metrics"`
+	NativeHistograms bool   `name:"native-histograms" env:"METRICS_NATIVE_HISTOGRAMS" default:"false"        help:"Expose probe latency as native histograms in addition to classic buckets"`
+}
+
+type Health struct {
+	ReadyIntervals int `name:"ready-intervals" env:"HEALTH_READY_INTERVALS" default:"3" help:"The number of probe intervals without a completed check cycle after which /readyz fails."`
 }
 
 type Serve struct {
-	Probe    Probe   `embed:"" prefix:"probe."`
-	Metrics  Metrics `embed:"" prefix:"metrics."`
-	LogLevel string  `                           name:"log.level" env:"LOG_LEVEL" default:"info" help:"Log level (debug, info, warn, error, fatal)"`
+	Config      string    `                             name:"config"       env:"CONFIG_FILE"                   help:"Path to a YAML file defining the hosts to check with per-host overrides of the probe flags." type:"existingfile"`
+	ConfigWatch bool      `                             name:"config.watch" env:"CONFIG_WATCH" default:"false" help:"Reload the configuration file whenever it changes, in addition to SIGHUP."`
+	Probe       Probe     `embed:"" prefix:"probe."`
+	Discovery   Discovery `embed:"" prefix:"discovery."`
+	Webhook     Webhook   `embed:"" prefix:"webhook."`
+	MQTT        MQTT      `embed:"" prefix:"mqtt."`
+	Push        Push      `embed:"" prefix:"push."`
+	Metrics     Metrics   `embed:"" prefix:"metrics."`
+	Health      Health    `embed:"" prefix:"health."`
+	LogLevel    string    `                             name:"log.level"    env:"LOG_LEVEL"    default:"info"  help:"Log level (debug, info, warn, error, fatal)"`
 }
 
-func serve(cli *CLI) error {
+func (s *Serve) Run() error {
 	ctx := context.Background()
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	logLevel, err := parseLogLevel(cli.Serve.LogLevel)
+	logLevel, err := parseLogLevel(s.LogLevel)
 	if err != nil {
 		return fmt.Errorf("failed to parse to log level: %w", err)
 	}
@@ -57,14 +144,17 @@ func serve(cli *CLI) error {
 		}),
 	)).With(logging.NewProgramAttr())
 
-	mdnsClient, err := mdns.New(
-		logger,
-		cli.Serve.Probe.UseIPv4,
-		cli.Serve.Probe.UseIPv6,
-		cli.Serve.Probe.IPv4Addr,
-		cli.Serve.Probe.IPv6Addr,
-		cli.Serve.Probe.Concurrency,
-	)
+	if s.Probe.Retries != nil {
+		logger.WarnContext(ctx, "--probe.retries is deprecated, use --probe.attempts instead")
+	}
+
+	hostsCfg, err := loadHostsConfig(s)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to load hosts configuration", logging.Error(err))
+		return err
+	}
+
+	mdnsClient, err := s.Probe.Socket.newClient(logger)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to create mdns checker", logging.Error(err))
 		return err
@@ -75,31 +165,109 @@ func serve(cli *CLI) error {
 		_ = mdnsClient.Close()
 	}()
 
-	exporter, err := prometheus.NewExporter()
+	exporter, err := prometheus.NewExporter(prometheus.ExporterOptions{
+		NativeHistograms: s.Metrics.NativeHistograms,
+	})
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to create prometheus exporter", logging.Error(err))
 		return err
 	}
 
+	exporter.SetHostLabels(hostsCfg.Labels)
+
 	mdnsProbe := mdns.NewProbe(mdnsClient)
+	store := memstore.New()
 
-	uc := usecase.NewCheckMDNSUseCase(
-		logger,
-		mdnsProbe,
-		prometheus.NewMDNSStatePublisher(logger, exporter),
-		cli.Serve.Probe.Timeout,
+	var (
+		checkProbe   ports.MDNSProbe        = mdnsProbe
+		serviceProbe ports.MDNSServiceProbe = mdns.NewServiceProbe(mdnsClient)
 	)
 
-	httpsrv := httpsrv.NewServer(cli.Serve.Metrics.Addr, httpsrv.ServerOptions{
-		MetricsHandler: exporter.Handler().ServeHTTP,
-	})
+	if s.Probe.Mode == probeModePassive {
+		monitor, err := mdns.NewPassiveMonitor(mdnsClient)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to start passive mdns monitor", logging.Error(err))
+			return err
+		}
+
+		defer monitor.Close()
+
+		checkProbe, serviceProbe = monitor, monitor
+	}
+
+	publishers := []ports.MDNSStatePublisher{prometheus.NewMDNSStatePublisher(logger, exporter), store}
+
+	mqttClient, mqttPublisher, err := s.MQTT.newPublisher(ctx, logger)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to connect to MQTT broker", logging.Error(err))
+		return err
+	}
+
+	if mqttClient != nil {
+		defer func() {
+			logger.InfoContext(ctx, "Closing MQTT client")
+			_ = mqttClient.Close()
+		}()
+
+		publishers = append(publishers, mqttPublisher)
+	}
+
+	// The pusher takes a snapshot of the metrics, so it is published to after the exporter.
+	pusher := s.Push.newPusher(logger, exporter)
+	if pusher != nil {
+		publishers = append(publishers, pusher)
+
+		go pusher.Run(ctx)
+	}
+
+	uc := usecase.NewCheckMDNSUseCase(logger, checkProbe, serviceProbe, publishers...)
+
+	notifier, err := s.Webhook.newNotifier(logger)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to create webhook notifier", logging.Error(err))
+		return err
+	}
+
+	if notifier != nil {
+		uc.Subscribe(notifier)
+
+		go notifier.Run(ctx)
+	}
+
+	interval := hostsCfg.tickInterval()
+	task := newTask(logger, uc, hostsCfg.Hosts)
+
+	var discoveryWorker *worker.Worker
+
+	if len(s.Discovery.Services) > 0 {
+		discoverUC := usecase.NewDiscoverHostsUseCase(logger, mdns.NewBrowser(mdnsClient))
+		discoveryWorker = worker.NewWorker(
+			logger,
+			s.Discovery.Interval,
+			newDiscoveryTask(logger, discoverUC, &s.Discovery, &s.Probe, task),
+		)
+	}
 
 	worker := worker.NewWorker(
 		logger,
-		cli.Serve.Probe.Interval,
-		newTask(logger, uc, cli.Serve.Probe.Hosts),
+		interval,
+		task,
 	)
 
+	reloader := newReloader(logger, s, hostsCfg, func(cfg *hostsConfig) {
+		exporter.SetHostLabels(cfg.Labels)
+		task.SetHosts(cfg.Hosts)
+		worker.SetInterval(cfg.tickInterval())
+	})
+
+	httpsrv := httpsrv.NewServer(s.Metrics.Addr, httpsrv.ServerOptions{
+		MetricsHandler:   exporter.Handler().ServeHTTP,
+		MetricsPath:      s.Metrics.Path,
+		ProbeHandler:     prometheus.NewProbeHandler(logger, mdnsProbe, s.Probe.Query.policy()),
+		HostStatusReader: store,
+		ReadinessChecks:  readinessChecks(mdnsClient, task, reloader, s.Health.ReadyIntervals),
+	})
+
 	defer func() {
 		logger.InfoContext(ctx, "Stopping...")
 		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -111,6 +279,14 @@ func serve(cli *CLI) error {
 			logger.ErrorContext(ctx, "Failed to stop Worker", logging.Error(serr))
 		}
 
+		if discoveryWorker != nil {
+			logger.InfoContext(ctx, "Stopping Discovery Worker...")
+			serr = discoveryWorker.Shutdown(shutdownCtx)
+			if serr != nil {
+				logger.ErrorContext(ctx, "Failed to stop Discovery Worker", logging.Error(serr))
+			}
+		}
+
 		logger.InfoContext(ctx, "Stopping HTTP Server...")
 		serr = httpsrv.Shutdown(shutdownCtx)
 		if serr != nil {
@@ -145,7 +321,10 @@ func serve(cli *CLI) error {
 	}()
 
 	go func() {
-		logger.InfoContext(ctx, "Start Worker", slog.Duration("interval", cli.Serve.Probe.Interval))
+		logger.InfoContext(ctx, "Start Worker",
+			slog.Duration("interval", interval),
+			slog.Int("hosts", len(hostsCfg.Hosts)),
+		)
 
 		err := worker.Start()
 		if err != nil {
@@ -154,6 +333,36 @@ func serve(cli *CLI) error {
 		}
 	}()
 
+	if discoveryWorker != nil {
+		go func() {
+			logger.InfoContext(ctx, "Start Discovery Worker",
+				slog.Duration("interval", s.Discovery.Interval),
+				slog.Any("services", s.Discovery.Services),
+			)
+
+			err := discoveryWorker.Start()
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to start Discovery Worker", logging.Error(err))
+				errCh <- err
+			}
+		}()
+	}
+
+	go reloader.Run(ctx)
+
+	go func() {
+		err := mdns.NewGoodbyeWatcher(mdnsClient).Run(ctx, func(ctx context.Context, goodbye ports.Goodbye) {
+			ctx = tracing.WithTraceID(ctx)
+
+			if err := uc.HandleGoodbye(ctx, goodbye); err != nil {
+				logger.ErrorContext(ctx, "Failed to publish goodbye", logging.Error(err))
+			}
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to watch goodbye packets", logging.Error(err))
+		}
+	}()
+
 	select {
 	case err := <-errCh:
 		return err
@@ -162,6 +371,26 @@ func serve(cli *CLI) error {
 	}
 }
 
+func readinessChecks(
+	mdnsClient *mdns.Client,
+	task *task,
+	reloader *reloader,
+	readyIntervals int,
+) map[string]httpsrv.ReadinessCheck {
+	return map[string]httpsrv.ReadinessCheck{
+		"mdns": func(context.Context) error {
+			if err := mdnsClient.Err(); err != nil {
+				return fmt.Errorf("mdns sockets are failing: %w", err)
+			}
+
+			return nil
+		},
+		"cycle": func(context.Context) error {
+			return task.checkRecent(time.Duration(readyIntervals) * reloader.Current().tickInterval())
+		},
+	}
+}
+
 type taskUC interface {
 	Execute(ctx context.Context, cmd usecase.CheckMDNSCommand) error
 }
@@ -169,10 +398,15 @@ type taskUC interface {
 type task struct {
 	logger *slog.Logger
 	uc     taskUC
-	hosts  []string
+
+	mu    sync.RWMutex
+	hosts []usecase.HostConfig
+	// discovered are the hosts found by discovery. They are checked unless configured explicitly.
+	discovered    []usecase.HostConfig
+	lastCompleted time.Time
 }
 
-func newTask(logger *slog.Logger, uc taskUC, hosts []string) *task {
+func newTask(logger *slog.Logger, uc taskUC, hosts []usecase.HostConfig) *task {
 	return &task{
 		logger: logger,
 		uc:     uc,
@@ -185,8 +419,10 @@ func (t *task) Execute(ctx context.Context) error {
 
 	t.logger.InfoContext(ctx, "Run MDNS check")
 
+	hosts := t.checkedHosts()
+
 	err := t.uc.Execute(ctx, usecase.CheckMDNSCommand{
-		Hosts: t.hosts,
+		Hosts: hosts,
 	})
 
 	if err != nil {
@@ -200,51 +436,125 @@ func (t *task) Execute(ctx context.Context) error {
 		t.logger.InfoContext(ctx, "Finished mdns check", slog.Duration("duration", time.Since(now)))
 	}
 
+	// Only a canceled cycle is incomplete. Failing to publish or notify the results, e.g. while an MQTT broker is
+	// down, does not stop the hosts from being checked, so it must not take the checker out of service.
+	if ctx.Err() == nil {
+		t.mu.Lock()
+		t.lastCompleted = time.Now()
+		t.mu.Unlock()
+	}
+
+	return nil
+}
+
+// checkRecent returns an error unless a check cycle has completed within the given period.
+func (t *task) checkRecent(period time.Duration) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.lastCompleted.IsZero() {
+		return errors.New("no completed check cycle yet")
+	}
+
+	if since := time.Since(t.lastCompleted); since > period {
+		return fmt.Errorf("last check cycle completed %s ago", since.Round(time.Second))
+	}
+
 	return nil
 }
 
-func (c *CLI) Validate() error {
+// SetHosts replaces the hosts checked from the next execution on.
+func (t *task) SetHosts(hosts []usecase.HostConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hosts = hosts
+}
+
+// SetDiscoveredHosts replaces the discovered hosts checked from the next execution on.
+func (t *task) SetDiscoveredHosts(hosts []usecase.HostConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.discovered = hosts
+}
+
+// checkedHosts returns the configured hosts followed by the discovered ones which are not configured.
+func (t *task) checkedHosts() []usecase.HostConfig {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.discovered) == 0 {
+		return t.hosts
+	}
+
+	configured := make(map[string]struct{}, len(t.hosts))
+	for _, h := range t.hosts {
+		configured[usecase.HostKey(h.Name)] = struct{}{}
+	}
+
+	hosts := slices.Clip(t.hosts)
+
+	for _, h := range t.discovered {
+		if _, ok := configured[usecase.HostKey(h.Name)]; !ok {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return hosts
+}
+
+func (s *Serve) Validate() error {
 	var errs []error
 
-	s := &c.Serve
 	p := &s.Probe
 
 	if p.Interval <= 0 {
 		errs = append(errs, fmt.Errorf("--probe.interval: must be greater than zero"))
 	}
 
-	if p.Timeout <= 0 {
-		errs = append(errs, fmt.Errorf("--probe.timeout: must be greater than zero"))
-	}
-
 	if p.Interval <= p.Timeout {
 		errs = append(errs, fmt.Errorf("--probe.interval: must be greater than --probe.timeout"))
 	}
 
-	if p.Concurrency <= 0 {
-		errs = append(errs, fmt.Errorf("--probe.concurrency: must be greater than zero"))
+	if p.FailThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("--probe.fail-threshold: must be greater than zero"))
 	}
 
-	if !p.UseIPv4 && !p.UseIPv6 {
-		errs = append(errs, errors.New("at least one of --probe.ipv4 or --probe.ipv6 must be enabled"))
+	if p.SuccessThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("--probe.success-threshold: must be greater than zero"))
 	}
 
-	if !isIP4Addr(p.IPv4Addr) {
-		errs = append(errs, fmt.Errorf("--probe.ipv4: must be a valid UDP IPv4 address e.g. 224.0.0.0:5353"))
+	if p.FlapThreshold < 0 {
+		errs = append(errs, fmt.Errorf("--probe.flap-threshold: must not be negative"))
 	}
 
-	if !isUDP4AddrResolvable(p.IPv4Addr) {
-		errs = append(errs, fmt.Errorf("--probe.ipv4: must be resolvable"))
+	if p.FlapWindow <= 0 {
+		errs = append(errs, fmt.Errorf("--probe.flap-window: must be greater than zero"))
 	}
 
-	if !isIP6Addr(p.IPv6Addr) {
-		errs = append(errs, fmt.Errorf("--probe.ipv6: must be a valid UDP IPv6 address e.g. [FF02::]:5353"))
+	if len(p.Hosts) == 0 && len(p.Services) == 0 && s.Config == "" && len(s.Discovery.Services) == 0 {
+		errs = append(errs, errors.New(
+			"at least one of --probe.hosts, --probe.services, --config or --discovery.services must be set",
+		))
 	}
 
-	if !isUDP6AddrResolvable(p.IPv6Addr) {
-		errs = append(errs, fmt.Errorf("--probe.ipv6: must be resolvable"))
+	errs = append(errs, s.Discovery.validate()...)
+	errs = append(errs, s.Webhook.validate()...)
+	errs = append(errs, s.MQTT.validate()...)
+	errs = append(errs, s.Push.validate()...)
+
+	if s.Health.ReadyIntervals <= 0 {
+		errs = append(errs, fmt.Errorf("--health.ready-intervals: must be greater than zero"))
+	}
+
+	if s.ConfigWatch && s.Config == "" {
+		errs = append(errs, errors.New("--config.watch: requires --config"))
 	}
 
+	errs = append(errs, p.Socket.validate("probe.")...)
+	errs = append(errs, p.Query.validate("probe.")...)
+
 	if !isTCPAddr(s.Metrics.Addr) {
 		errs = append(errs, fmt.Errorf("--metrics.addr: must be a valid tcp listening address e.g. 0.0.0.0:8080"))
 	}
@@ -260,6 +570,86 @@ func (c *CLI) Validate() error {
 	return nil
 }
 
+func (s *Socket) newClient(logger *slog.Logger) (*mdns.Client, error) {
+	return mdns.New(logger, s.UseIPv4, s.UseIPv6, s.IPv4Addr, s.IPv6Addr, s.Concurrency)
+}
+
+func (q *Query) policy() ports.QueryPolicy {
+	policy := ports.QueryPolicy{
+		Timeout:         q.Timeout,
+		Attempts:        q.Attempts,
+		AttemptInterval: q.AttemptInterval,
+		AnswerWindow:    q.AnswerWindow,
+	}
+
+	if q.Retries != nil {
+		policy.Attempts = *q.Retries + 1
+	}
+
+	return policy
+}
+
+// validate checks the query policy, flagPrefix is the prefix of the flags in the command they belong to.
+func (q *Query) validate(flagPrefix string) []error {
+	var errs []error
+
+	if q.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("--%stimeout: must be greater than zero", flagPrefix))
+	}
+
+	switch {
+	case q.Retries != nil && *q.Retries < 0:
+		errs = append(errs, fmt.Errorf("--%sretries: must not be negative", flagPrefix))
+	case q.Retries == nil && q.Attempts <= 0:
+		errs = append(errs, fmt.Errorf("--%sattempts: must be greater than zero", flagPrefix))
+	}
+
+	if q.AttemptInterval <= 0 {
+		errs = append(errs, fmt.Errorf("--%sattempt-interval: must be greater than zero", flagPrefix))
+	}
+
+	if q.AnswerWindow <= 0 {
+		errs = append(errs, fmt.Errorf("--%sanswer-window: must be greater than zero", flagPrefix))
+	}
+
+	if q.Timeout > 0 && q.AttemptInterval > 0 && !q.policy().AttemptsFit() {
+		errs = append(errs, fmt.Errorf("--%[1]sattempts: the last attempt must be sent within --%[1]stimeout", flagPrefix))
+	}
+
+	return errs
+}
+
+// validate checks the socket options, flagPrefix is the prefix of the flags in the command they belong to.
+func (s *Socket) validate(flagPrefix string) []error {
+	var errs []error
+
+	if s.Concurrency <= 0 {
+		errs = append(errs, fmt.Errorf("--%sconcurrency: must be greater than zero", flagPrefix))
+	}
+
+	if !s.UseIPv4 && !s.UseIPv6 {
+		errs = append(errs, fmt.Errorf("at least one of --%sipv4 or --%sipv6 must be enabled", flagPrefix, flagPrefix))
+	}
+
+	if !isIP4Addr(s.IPv4Addr) {
+		errs = append(errs, fmt.Errorf("--%sipv4: must be a valid UDP IPv4 address e.g. 224.0.0.0:5353", flagPrefix))
+	}
+
+	if !isUDP4AddrResolvable(s.IPv4Addr) {
+		errs = append(errs, fmt.Errorf("--%sipv4: must be resolvable", flagPrefix))
+	}
+
+	if !isIP6Addr(s.IPv6Addr) {
+		errs = append(errs, fmt.Errorf("--%sipv6: must be a valid UDP IPv6 address e.g. [FF02::]:5353", flagPrefix))
+	}
+
+	if !isUDP6AddrResolvable(s.IPv6Addr) {
+		errs = append(errs, fmt.Errorf("--%sipv6: must be resolvable", flagPrefix))
+	}
+
+	return errs
+}
+
 func parseLogLevel(levelStr string) (slog.Level, error) {
 	switch strings.ToLower(levelStr) {
 	case "debug":
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/usecase"
+)
+
+type stubTaskUC struct {
+	err error
+}
+
+func (s *stubTaskUC) Execute(ctx context.Context, _ usecase.CheckMDNSCommand) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.err
+}
+
+func TestTask_CompletesCycleDespiteFailedPublications(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tk := newTask(logger, &stubTaskUC{err: errors.New("failed to publish mdns check results: not connected")}, nil)
+
+	require.ErrorContains(t, tk.checkRecent(time.Minute), "no completed check cycle yet")
+
+	require.NoError(t, tk.Execute(t.Context()))
+	require.NoError(t, tk.checkRecent(time.Minute))
+}
+
+func TestTask_DoesNotCompleteCanceledCycle(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tk := newTask(logger, &stubTaskUC{}, nil)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	require.NoError(t, tk.Execute(ctx))
+	require.ErrorContains(t, tk.checkRecent(time.Minute), "no completed check cycle yet")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+
+	"github.com/khmm12/mdns-health-checker/internal/adapter/webhook"
+)
+
+// newNotifier returns the webhook notifier, or nil if the webhook is not enabled.
+func (w *Webhook) newNotifier(logger *slog.Logger) (*webhook.Notifier, error) {
+	if w.URL == "" {
+		return nil, nil
+	}
+
+	var tmpl string
+
+	if w.Template != "" {
+		b, err := os.ReadFile(w.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook template: %w", err)
+		}
+
+		tmpl = string(b)
+	}
+
+	return webhook.NewNotifier(logger, webhook.Options{
+		URL:       w.URL,
+		Template:  tmpl,
+		Headers:   w.Headers,
+		Secret:    w.Secret,
+		Timeout:   w.Timeout,
+		Retries:   w.Retries,
+		Backoff:   w.Backoff,
+		QueueSize: w.QueueSize,
+	})
+}
+
+func (w *Webhook) validate() []error {
+	if w.URL == "" {
+		return nil
+	}
+
+	var errs []error
+
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.New("--webhook.url: must be an http or https URL"))
+	}
+
+	if w.Timeout <= 0 {
+		errs = append(errs, errors.New("--webhook.timeout: must be greater than zero"))
+	}
+
+	if w.Retries < 0 {
+		errs = append(errs, errors.New("--webhook.retries: must not be negative"))
+	}
+
+	if w.Backoff <= 0 {
+		errs = append(errs, errors.New("--webhook.backoff: must be greater than zero"))
+	}
+
+	if w.QueueSize <= 0 {
+		errs = append(errs, errors.New("--webhook.queue-size: must be greater than zero"))
+	}
+
+	return errs
+}
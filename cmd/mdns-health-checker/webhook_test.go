@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Validate(t *testing.T) {
+	w := &Webhook{
+		URL:       "ftp://example.com/hook",
+		Timeout:   10 * time.Second,
+		Retries:   -1,
+		Backoff:   time.Second,
+		QueueSize: 0,
+	}
+
+	err := errors.Join(w.validate()...)
+	require.ErrorContains(t, err, "--webhook.url: must be an http or https URL")
+	require.ErrorContains(t, err, "--webhook.retries: must not be negative")
+	require.ErrorContains(t, err, "--webhook.queue-size: must be greater than zero")
+	require.NotContains(t, err.Error(), "--webhook.timeout")
+
+	// Zero retries only deliver once, and without a URL the webhook is disabled.
+	w.URL, w.Retries, w.QueueSize = "https://example.com/hook", 0, 1
+	require.Empty(t, w.validate())
+	require.Empty(t, (&Webhook{Retries: -1}).validate())
+}
+
+func TestWebhook_NewNotifier(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	n, err := (&Webhook{}).newNotifier(logger)
+	require.NoError(t, err)
+	require.Nil(t, n)
+
+	path := filepath.Join(t.TempDir(), "body.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"text": {{ .Host | json }`), 0o600))
+
+	_, err = (&Webhook{URL: "http://localhost/hook", Template: path, QueueSize: 1}).newNotifier(logger)
+	require.ErrorContains(t, err, "failed to parse webhook template")
+}
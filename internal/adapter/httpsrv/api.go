@@ -0,0 +1,93 @@
+package httpsrv
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+type hostResponse struct {
+	Host        string     `json:"host"`
+	State       string     `json:"state"`
+	LastCheck   time.Time  `json:"last_check"`
+	LastChange  time.Time  `json:"last_change"`
+	LastSuccess *time.Time `json:"last_success"`
+	LastSeen    *time.Time `json:"last_seen,omitempty"`
+	RTTSeconds  float64    `json:"rtt_seconds"`
+	Addresses   []string   `json:"addresses"`
+	Family      string     `json:"family"`
+	Reason      string     `json:"reason,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type hostsResponse struct {
+	Hosts []hostResponse `json:"hosts"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func hostsHandler(reader ports.HostStatusReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := reader.Hosts(r.Context())
+
+		resp := hostsResponse{Hosts: make([]hostResponse, 0, len(statuses))}
+		for _, s := range statuses {
+			resp.Hosts = append(resp.Hosts, newHostResponse(s))
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func hostHandler(reader ports.HostStatusReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, ok := reader.Host(r.Context(), r.PathValue("name"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "host not found"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newHostResponse(status))
+	}
+}
+
+func newHostResponse(s ports.HostStatus) hostResponse {
+	resp := hostResponse{
+		Host:       s.Host,
+		State:      s.State.String(),
+		LastCheck:  s.LastCheck,
+		LastChange: s.LastChange,
+		RTTSeconds: s.RTT.Seconds(),
+		Addresses:  make([]string, 0, len(s.Addrs)),
+		Family:     s.Family.String(),
+		Reason:     s.Reason,
+	}
+
+	if !s.LastSuccess.IsZero() {
+		resp.LastSuccess = &s.LastSuccess
+	}
+
+	if !s.LastSeen.IsZero() {
+		resp.LastSeen = &s.LastSeen
+	}
+
+	for _, addr := range s.Addrs {
+		resp.Addresses = append(resp.Addresses, addr.String())
+	}
+
+	if s.Err != nil {
+		resp.Error = s.Err.Error()
+	}
+
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
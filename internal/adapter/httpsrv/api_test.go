@@ -0,0 +1,98 @@
+package httpsrv
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	portsm "github.com/khmm12/mdns-health-checker/internal/ports/mocks"
+)
+
+func TestAPI_ListsHosts(t *testing.T) {
+	checkedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	reader := portsm.NewMockHostStatusReader(t)
+	reader.On("Hosts", mock.Anything).Return([]ports.HostStatus{
+		{
+			Host:        "printer.local",
+			State:       ports.HostUp,
+			LastCheck:   checkedAt,
+			LastChange:  checkedAt.Add(-time.Hour),
+			LastSuccess: checkedAt,
+			RTT:         25 * time.Millisecond,
+			Addrs:       []netip.Addr{netip.MustParseAddr("192.168.1.10")},
+			Family:      ports.FamilyIPv4,
+		},
+		{
+			Host:       "switch.local",
+			State:      ports.HostError,
+			LastCheck:  checkedAt,
+			LastChange: checkedAt,
+			Err:        errors.New("connection is closed"),
+		},
+	})
+
+	rec := serveAPI(t, reader, "/api/v1/hosts")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"hosts": [
+		{
+			"host": "printer.local",
+			"state": "up",
+			"last_check": "2025-01-01T12:00:00Z",
+			"last_change": "2025-01-01T11:00:00Z",
+			"last_success": "2025-01-01T12:00:00Z",
+			"rtt_seconds": 0.025,
+			"addresses": ["192.168.1.10"],
+			"family": "ipv4"
+		},
+		{
+			"host": "switch.local",
+			"state": "error",
+			"last_check": "2025-01-01T12:00:00Z",
+			"last_change": "2025-01-01T12:00:00Z",
+			"last_success": null,
+			"rtt_seconds": 0,
+			"addresses": [],
+			"family": "unknown",
+			"error": "connection is closed"
+		}
+	]}`, rec.Body.String())
+}
+
+func TestAPI_GetsHost(t *testing.T) {
+	reader := portsm.NewMockHostStatusReader(t)
+	reader.On("Host", mock.Anything, "printer.local").
+		Return(ports.HostStatus{Host: "printer.local", State: ports.HostDown}, true)
+	reader.On("Host", mock.Anything, "missing.local").Return(ports.HostStatus{}, false)
+
+	rec := serveAPI(t, reader, "/api/v1/hosts/printer.local")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"state":"down"`)
+
+	rec = serveAPI(t, reader, "/api/v1/hosts/missing.local")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.JSONEq(t, `{"error": "host not found"}`, rec.Body.String())
+}
+
+func serveAPI(t *testing.T, reader ports.HostStatusReader, url string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	srv := NewServer("127.0.0.1:0", ServerOptions{
+		MetricsHandler:   func(http.ResponseWriter, *http.Request) {},
+		HostStatusReader: reader,
+	})
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+	return rec
+}
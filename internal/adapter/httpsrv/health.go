@@ -1,6 +1,16 @@
 package httpsrv
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ReadinessCheck returns an error describing why the service is not ready, or nil when it is.
+type ReadinessCheck func(ctx context.Context) error
 
 func healthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
@@ -8,3 +18,27 @@ func healthHandler() http.HandlerFunc {
 		_, _ = w.Write([]byte("OK"))
 	}
 }
+
+func readyHandler(checks map[string]ReadinessCheck) http.HandlerFunc {
+	names := slices.Sorted(maps.Keys(checks))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var failures []string
+
+		for _, name := range names {
+			if err := checks[name](r.Context()); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", name, err))
+			}
+		}
+
+		if len(failures) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(strings.Join(failures, "\n")))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	}
+}
@@ -0,0 +1,38 @@
+package httpsrv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyz_ReportsFailingChecks(t *testing.T) {
+	var cycleErr error
+
+	srv := NewServer("127.0.0.1:0", ServerOptions{
+		MetricsHandler: func(http.ResponseWriter, *http.Request) {},
+		ReadinessChecks: map[string]ReadinessCheck{
+			"cycle": func(context.Context) error { return cycleErr },
+			"mdns":  func(context.Context) error { return nil },
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	cycleErr = errors.New("no completed check cycle yet")
+
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "cycle: no completed check cycle yet", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
 )
 
 type Server struct {
@@ -15,6 +17,12 @@ type Server struct {
 type ServerOptions struct {
 	MetricsHandler http.HandlerFunc
 	MetricsPath    string
+	// ProbeHandler serves on-demand probes at /probe. The endpoint is disabled when nil.
+	ProbeHandler http.Handler
+	// HostStatusReader backs the /api/v1/hosts endpoints. The endpoints are disabled when nil.
+	HostStatusReader ports.HostStatusReader
+	// ReadinessChecks must all pass for /readyz to succeed, by check name.
+	ReadinessChecks map[string]ReadinessCheck
 }
 
 func NewServer(addr string, opts ServerOptions) *Server {
@@ -31,8 +39,19 @@ func NewServer(addr string, opts ServerOptions) *Server {
 	}
 
 	router.Handle("/health", healthHandler())
+	router.Handle("/livez", healthHandler())
+	router.Handle("/readyz", readyHandler(opts.ReadinessChecks))
 	router.Handle(opts.MetricsPath, opts.MetricsHandler)
 
+	if opts.ProbeHandler != nil {
+		router.Handle("/probe", opts.ProbeHandler)
+	}
+
+	if opts.HostStatusReader != nil {
+		router.Handle("GET /api/v1/hosts", hostsHandler(opts.HostStatusReader))
+		router.Handle("GET /api/v1/hosts/{name}", hostHandler(opts.HostStatusReader))
+	}
+
 	return &Server{
 		srv:    srv,
 		router: router,
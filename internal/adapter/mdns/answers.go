@@ -0,0 +1,64 @@
+package mdns
+
+import (
+	"net/netip"
+	"slices"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// hostAnswers collects the address records of a host from every responder, in the order they were received.
+type hostAnswers struct {
+	host    string
+	sources []netip.Addr
+	addrs   []netip.Addr
+}
+
+func newHostAnswers(host string) *hostAnswers {
+	return &hostAnswers{host: canonicalName(host)}
+}
+
+func (a *hostAnswers) add(msg message) bool {
+	addrs := hostAddrs(msg.Message, a.host)
+	if len(addrs) == 0 {
+		return false
+	}
+
+	for _, addr := range addrs {
+		a.addrs = appendUnique(a.addrs, addr)
+	}
+
+	// The same responder is reached through every interface on the link, so the zone is irrelevant.
+	if msg.Src.IsValid() {
+		a.sources = appendUnique(a.sources, msg.Src.WithZone(""))
+	}
+
+	return true
+}
+
+func hostAddrs(msg dnsmessage.Message, host string) []netip.Addr {
+	var addrs []netip.Addr
+
+	for _, r := range slices.Concat(msg.Answers, msg.Additionals) {
+		if r.Header.TTL == 0 || canonicalName(r.Header.Name.String()) != host {
+			continue
+		}
+
+		switch body := r.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(body.A))
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(body.AAAA))
+		}
+	}
+
+	return addrs
+}
+
+func appendUnique(addrs []netip.Addr, addr netip.Addr) []netip.Addr {
+	if slices.Contains(addrs, addr) {
+		return addrs
+	}
+
+	return append(addrs, addr)
+}
@@ -0,0 +1,55 @@
+package mdns
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestHostAddrs(t *testing.T) {
+	answer := func(rs ...dnsmessage.Resource) dnsmessage.Message {
+		return dnsmessage.Message{Answers: rs}
+	}
+
+	a := resource("Printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}})
+	aaaa := resource("printer.local.", &dnsmessage.AAAAResource{AAAA: [16]byte{0xfe, 0x80, 15: 1}})
+
+	require.Equal(t, []netip.Addr{
+		netip.MustParseAddr("192.168.1.10"),
+		netip.MustParseAddr("fe80::1"),
+	}, hostAddrs(answer(a, aaaa), "printer.local"))
+	require.Empty(t, hostAddrs(answer(a), "switch.local"))
+	require.Empty(t, hostAddrs(answer(withTTL(a, 0)), "printer.local"), "a goodbye is no answer")
+	require.Empty(t, hostAddrs(answer(resource("printer.local.", &dnsmessage.TXTResource{TXT: []string{"a=1"}})),
+		"printer.local"))
+}
+
+func TestHostAnswers_CollectsEveryResponder(t *testing.T) {
+	answers := newHostAnswers("Printer.local.")
+
+	answer := func(src string, a [4]byte) message {
+		return message{
+			Src: netip.MustParseAddr(src),
+			Message: dnsmessage.Message{Answers: []dnsmessage.Resource{
+				resource("printer.local.", &dnsmessage.AResource{A: a}),
+			}},
+		}
+	}
+
+	require.True(t, answers.add(answer("192.168.1.20", [4]byte{192, 168, 1, 20})))
+	require.True(t, answers.add(answer("192.168.1.10", [4]byte{192, 168, 1, 10})))
+	require.True(t, answers.add(answer("192.168.1.20", [4]byte{192, 168, 1, 20})))
+	require.False(t, answers.add(message{
+		Src:     netip.MustParseAddr("192.168.1.30"),
+		Message: dnsmessage.Message{Answers: []dnsmessage.Resource{resource("switch.local.", &dnsmessage.AResource{})}},
+	}))
+
+	// The first answer comes first.
+	require.Equal(t, []netip.Addr{
+		netip.MustParseAddr("192.168.1.20"),
+		netip.MustParseAddr("192.168.1.10"),
+	}, answers.sources)
+	require.Equal(t, answers.sources, answers.addrs)
+}
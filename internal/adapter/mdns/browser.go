@@ -0,0 +1,52 @@
+package mdns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// Browser discovers DNS-SD services over the client's multicast sockets.
+type Browser struct {
+	client *Client
+}
+
+func NewBrowser(client *Client) *Browser {
+	return &Browser{client: client}
+}
+
+func (b *Browser) ServiceTypes(ctx context.Context, timeout time.Duration) ([]string, error) {
+	set, err := b.client.lookup(ctx, &lookupQuery{
+		policy:    ports.QueryPolicy{Timeout: timeout},
+		questions: []dnsmessage.Question{newQuestion(servicesMetaQuery, dnsmessage.TypePTR)},
+		follow:    func(*recordSet) ([]dnsmessage.Question, bool) { return nil, false },
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return set.serviceTypes(), nil
+}
+
+func (b *Browser) Browse(ctx context.Context, service string, timeout time.Duration) ([]ports.ServiceInstance, error) {
+	service = serviceFQDN(service)
+
+	if _, err := dnsmessage.NewName(service + "."); err != nil {
+		return nil, fmt.Errorf("invalid service type %q: %w", service, err)
+	}
+
+	set, err := b.client.lookup(ctx, &lookupQuery{
+		policy:    ports.QueryPolicy{Timeout: timeout},
+		questions: []dnsmessage.Question{newQuestion(service, dnsmessage.TypePTR)},
+		follow:    func(set *recordSet) ([]dnsmessage.Question, bool) { return set.missing(service), false },
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return set.instances(service), nil
+}
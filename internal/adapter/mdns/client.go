@@ -1,21 +1,25 @@
 package mdns
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net"
+	"sync/atomic"
 
-	"github.com/pion/mdns/v2"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
 	"golang.org/x/sync/semaphore"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
 )
 
 type Client struct {
 	logger      *slog.Logger
-	conn        *mdns.Conn
+	transport   *transport
 	concurrency int
 	sem         *semaphore.Weighted
+	closed      atomic.Bool
 }
 
 func New(logger *slog.Logger, useIPv4, useIPv6 bool, ipv4Addr, ipv6Addr string, concurrency int) (*Client, error) {
@@ -23,49 +27,48 @@ func New(logger *slog.Logger, useIPv4, useIPv6 bool, ipv4Addr, ipv6Addr string,
 		return nil, fmt.Errorf("mdns: probe concurrency must be greater than zero")
 	}
 
-	conn, err := buildServer(useIPv4, useIPv6, ipv4Addr, ipv6Addr)
+	transport, err := newTransport(logger, useIPv4, useIPv6, ipv4Addr, ipv6Addr)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to init mdns transport: %w", err)
 	}
 
 	return &Client{
 		logger:      logger,
-		conn:        conn,
+		transport:   transport,
 		concurrency: concurrency,
 		sem:         semaphore.NewWeighted(int64(concurrency)),
 	}, nil
 }
 
 func (c *Client) Close() error {
-	return c.conn.Close()
-}
+	c.closed.Store(true)
 
-func buildServer(useIPv4, useIPv6 bool, ipv4Addr, ipv6Addr string) (*mdns.Conn, error) {
-	var err error
+	return c.transport.close()
+}
 
-	var packetConnV4 *ipv4.PacketConn
+// Closed reports whether Close has been called on the client.
+func (c *Client) Closed() bool {
+	return c.closed.Load()
+}
 
-	if useIPv4 {
-		packetConnV4, err = buildV4Conn(ipv4Addr)
-		if err != nil {
-			return nil, err
-		}
+// Err returns why the sockets of the client fail to send or receive, or nil while they work.
+func (c *Client) Err() error {
+	if c.Closed() {
+		return errTransportClosed
 	}
 
-	var packetConnV6 *ipv6.PacketConn
-	if useIPv6 {
-		packetConnV6, err = buildV6Conn(ipv6Addr)
-		if err != nil {
-			return nil, err
-		}
-	}
+	return c.transport.err()
+}
 
-	server, err := mdns.Server(packetConnV4, packetConnV6, &mdns.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to init mdns server: %w", err)
+func (c *Client) classifyError(ctx context.Context) ports.ErrorClass {
+	switch {
+	case ctx.Err() != nil:
+		return ports.ErrorClassCanceled
+	case c.Closed():
+		return ports.ErrorClassClosed
+	default:
+		return ports.ErrorClassOther
 	}
-
-	return server, nil
 }
 
 func buildV4Conn(addr string) (*ipv4.PacketConn, error) {
@@ -0,0 +1,94 @@
+package mdns
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// GoodbyeWatcher reports the hosts and service instances withdrawn with goodbye packets as they are received.
+type GoodbyeWatcher struct {
+	client *Client
+}
+
+func NewGoodbyeWatcher(client *Client) *GoodbyeWatcher {
+	return &GoodbyeWatcher{client: client}
+}
+
+// Run calls handle for every goodbye until ctx is canceled or the client is closed.
+func (w *GoodbyeWatcher) Run(ctx context.Context, handle func(ctx context.Context, goodbye ports.Goodbye)) error {
+	messages, unsubscribe, err := w.client.transport.subscribe()
+	if err != nil {
+		return err
+	}
+
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			for _, g := range goodbyes(msg) {
+				handle(ctx, g)
+			}
+		}
+	}
+}
+
+// goodbyes returns the names the message withdraws. An address goodbye next to a new address is an address change.
+func goodbyes(msg message) []ports.Goodbye {
+	var (
+		hosts     []string
+		instances []string
+		announced = make(map[string]struct{})
+	)
+
+	for _, r := range slices.Concat(msg.Answers, msg.Additionals) {
+		name := canonicalName(r.Header.Name.String())
+
+		switch body := r.Body.(type) {
+		case *dnsmessage.AResource, *dnsmessage.AAAAResource:
+			if r.Header.TTL > 0 {
+				announced[name] = struct{}{}
+			} else {
+				hosts = append(hosts, name)
+			}
+		case *dnsmessage.SRVResource:
+			if r.Header.TTL == 0 {
+				instances = append(instances, name)
+			}
+		case *dnsmessage.PTRResource:
+			if r.Header.TTL == 0 && name != servicesMetaQuery && strings.HasPrefix(name, "_") {
+				instances = append(instances, canonicalName(body.PTR.String()))
+			}
+		}
+	}
+
+	var out []ports.Goodbye
+
+	for _, name := range compactSorted(hosts) {
+		if _, ok := announced[name]; !ok {
+			out = append(out, ports.Goodbye{Name: name, ReceivedAt: msg.ReceivedAt})
+		}
+	}
+
+	for _, name := range compactSorted(instances) {
+		out = append(out, ports.Goodbye{Name: name, Service: true, ReceivedAt: msg.ReceivedAt})
+	}
+
+	return out
+}
+
+func compactSorted(names []string) []string {
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,42 @@
+package mdns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func TestGoodbyes(t *testing.T) {
+	msg := message{
+		ReceivedAt: passiveNow,
+		Message: dnsmessage.Message{
+			Answers: []dnsmessage.Resource{
+				withTTL(resource("_ipp._tcp.local.", &dnsmessage.PTRResource{
+					PTR: dnsmessage.MustNewName("Office Printer._ipp._tcp.local."),
+				}), 0),
+				withTTL(resource("Office Printer._ipp._tcp.local.", &dnsmessage.SRVResource{
+					Port: 631, Target: dnsmessage.MustNewName("printer.local."),
+				}), 0),
+				withTTL(resource("_services._dns-sd._udp.local.", &dnsmessage.PTRResource{
+					PTR: dnsmessage.MustNewName("_ipp._tcp.local."),
+				}), 0),
+				withTTL(resource("Printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}), 0),
+				withTTL(resource("printer.local.", &dnsmessage.AAAAResource{
+					AAAA: [16]byte{0xfe, 0x80, 15: 1},
+				}), 0),
+				// A goodbye of the old address next to the new one is an address change.
+				withTTL(resource("switch.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}}), 0),
+				resource("switch.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 21}}),
+				resource("nas.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 30}}),
+			},
+		},
+	}
+
+	require.Equal(t, []ports.Goodbye{
+		{Name: "printer.local", ReceivedAt: passiveNow},
+		{Name: "office printer._ipp._tcp.local", Service: true, ReceivedAt: passiveNow},
+	}, goodbyes(msg))
+}
@@ -0,0 +1,150 @@
+package mdns
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// followFunc returns the questions for the records still missing, and whether the lookup is done.
+type followFunc func(set *recordSet) ([]dnsmessage.Question, bool)
+
+// lookupQuery asks for a set of records, following up on the records the answers point to.
+type lookupQuery struct {
+	policy    ports.QueryPolicy
+	questions []dnsmessage.Question
+	follow    followFunc
+
+	send     func(questions ...dnsmessage.Question) error
+	schedule *resendSchedule
+	asked    map[dnsmessage.Question]struct{}
+	set      *recordSet
+	sentAt   time.Time
+}
+
+// lookup collects the records until follow reports the lookup done or the timeout of the policy elapses.
+func (c *Client) lookup(ctx context.Context, q *lookupQuery) (*recordSet, error) {
+	if err := c.sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	defer c.sem.Release(1)
+
+	msgs, unsubscribe, err := c.transport.subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	defer unsubscribe()
+
+	innerCtx, cancel := context.WithTimeout(ctx, q.policy.Timeout)
+	defer cancel()
+
+	q.send = c.transport.query
+
+	set, err := q.run(innerCtx, msgs)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return set, err
+}
+
+func (q *lookupQuery) run(ctx context.Context, msgs <-chan message) (*recordSet, error) {
+	q.schedule = newResendSchedule(q.policy)
+	defer q.schedule.stop()
+
+	q.set = newRecordSet()
+	q.asked = make(map[dnsmessage.Question]struct{}, len(q.questions))
+
+	for _, question := range q.questions {
+		q.asked[question] = struct{}{}
+	}
+
+	if err := q.query(q.questions); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return q.set, nil
+		case <-q.schedule.due():
+			if err := q.resend(); err != nil {
+				return nil, err
+			}
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil, errTransportClosed
+			}
+
+			q.set.addMessage(msg.Message)
+
+			done, err := q.askMissing()
+			if err != nil {
+				return nil, err
+			}
+
+			if done {
+				return q.set, nil
+			}
+		}
+	}
+}
+
+func (q *lookupQuery) attempts() int {
+	return q.schedule.attempts
+}
+
+func (q *lookupQuery) query(questions []dnsmessage.Question) error {
+	if err := q.send(questions...); err != nil {
+		return err
+	}
+
+	q.schedule.sent()
+	q.sentAt = time.Now()
+
+	return nil
+}
+
+func (q *lookupQuery) resend() error {
+	questions := slices.Clone(q.questions)
+
+	missing, _ := q.follow(q.set)
+	for _, question := range missing {
+		q.asked[question] = struct{}{}
+
+		if !slices.Contains(questions, question) {
+			questions = append(questions, question)
+		}
+	}
+
+	return q.query(questions)
+}
+
+func (q *lookupQuery) askMissing() (bool, error) {
+	missing, done := q.follow(q.set)
+	if done {
+		return true, nil
+	}
+
+	var next []dnsmessage.Question
+
+	for _, question := range missing {
+		if _, ok := q.asked[question]; !ok {
+			q.asked[question] = struct{}{}
+			next = append(next, question)
+		}
+	}
+
+	if len(next) == 0 {
+		return false, nil
+	}
+
+	return false, q.send(next...)
+}
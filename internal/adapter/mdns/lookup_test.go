@@ -0,0 +1,81 @@
+package mdns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func TestLookupQuery_ResendsUntilTimeout(t *testing.T) {
+	q, sent := newTestLookupQuery(ports.QueryPolicy{Attempts: 3, AttemptInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := q.run(ctx, make(chan message))
+	require.NoError(t, err)
+
+	require.Equal(t, 3, q.attempts())
+	require.Len(t, *sent, 3)
+
+	for _, questions := range *sent {
+		require.Equal(t, q.questions, questions)
+	}
+}
+
+func TestLookupQuery_ResendsMissingRecords(t *testing.T) {
+	q, sent := newTestLookupQuery(ports.QueryPolicy{Attempts: 2, AttemptInterval: 10 * time.Millisecond})
+
+	msgs := make(chan message, 1)
+	msgs <- message{Message: dnsmessage.Message{Answers: []dnsmessage.Resource{
+		resource("Bridge._hap._tcp.local.", &dnsmessage.SRVResource{
+			Port: 80, Target: dnsmessage.MustNewName("bridge.local."),
+		}),
+	}}}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := q.run(ctx, msgs)
+	require.NoError(t, err)
+
+	addrs := []dnsmessage.Question{
+		newQuestion("bridge.local", dnsmessage.TypeA),
+		newQuestion("bridge.local", dnsmessage.TypeAAAA),
+	}
+
+	// The follow-up is asked right away, and again next to the initial questions with the second attempt.
+	require.Equal(t, 2, q.attempts())
+	require.Equal(t, [][]dnsmessage.Question{
+		q.questions,
+		addrs,
+		append(q.questions, addrs...),
+	}, *sent)
+}
+
+func newTestLookupQuery(policy ports.QueryPolicy) (*lookupQuery, *[][]dnsmessage.Question) {
+	var sent [][]dnsmessage.Question
+
+	q := &lookupQuery{
+		policy: policy,
+		questions: []dnsmessage.Question{
+			newQuestion("_hap._tcp.local", dnsmessage.TypePTR),
+			newQuestion("Bridge._hap._tcp.local", dnsmessage.TypeSRV),
+			newQuestion("Bridge._hap._tcp.local", dnsmessage.TypeTXT),
+		},
+		follow: func(set *recordSet) ([]dnsmessage.Question, bool) {
+			return set.missingInstance("Bridge._hap._tcp.local"), false
+		},
+		send: func(questions ...dnsmessage.Question) error {
+			sent = append(sent, questions)
+			return nil
+		},
+	}
+
+	return q, &sent
+}
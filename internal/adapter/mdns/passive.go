@@ -0,0 +1,298 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+const (
+	// goodbyeTTL is how long a withdrawn record is kept (RFC 6762 §10.1).
+	goodbyeTTL    = time.Second
+	cacheFlushBit = 1 << 15
+	pruneInterval = time.Minute
+	pruneAfter    = time.Hour
+)
+
+// PassiveMonitor reports a name up while one of the records it announced without being queried is within its TTL.
+type PassiveMonitor struct {
+	client      *Client
+	unsubscribe func()
+	done        chan struct{}
+	now         func() time.Time
+
+	mu        sync.Mutex
+	names     map[string]*observedName
+	lastPrune time.Time
+}
+
+// NewPassiveMonitor starts observing the responses received by the client.
+func NewPassiveMonitor(client *Client) (*PassiveMonitor, error) {
+	messages, unsubscribe, err := client.transport.subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	m := newPassiveMonitor(client)
+	m.unsubscribe = unsubscribe
+
+	go func() {
+		defer close(m.done)
+
+		for msg := range messages {
+			m.observe(msg)
+		}
+	}()
+
+	return m, nil
+}
+
+func newPassiveMonitor(client *Client) *PassiveMonitor {
+	return &PassiveMonitor{
+		client:      client,
+		unsubscribe: func() {},
+		done:        make(chan struct{}),
+		now:         time.Now,
+		names:       make(map[string]*observedName),
+	}
+}
+
+func (m *PassiveMonitor) Close() error {
+	m.unsubscribe()
+	<-m.done
+
+	return nil
+}
+
+func (m *PassiveMonitor) Probe(_ context.Context, host string, _ ports.QueryPolicy) (ports.ProbeResult, error) {
+	if err := m.checkClosed(); err != nil {
+		return ports.ProbeResult{}, err
+	}
+
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := m.names[canonicalName(host)]
+
+	addrs := name.liveAddrs(now)
+	if len(addrs) == 0 {
+		return ports.ProbeResult{State: ports.HostDown, LastSeen: name.seenAt()}, nil
+	}
+
+	return ports.ProbeResult{
+		State:    ports.HostUp,
+		Addrs:    addrs,
+		Family:   familyOf(addrs[0]),
+		LastSeen: name.seenAt(),
+	}, nil
+}
+
+// ProbeService does not require the PTR record of the service type, responders only announce it when they start.
+func (m *PassiveMonitor) ProbeService(
+	_ context.Context,
+	check ports.ServiceCheck,
+	_ ports.QueryPolicy,
+) (ports.ProbeResult, error) {
+	label, service, err := splitInstanceName(check.Instance)
+	if err != nil {
+		return ports.ProbeResult{}, &ports.ProbeError{Class: ports.ErrorClassOther, Err: err}
+	}
+
+	if err := m.checkClosed(); err != nil {
+		return ports.ProbeResult{}, err
+	}
+
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inst := ports.ServiceInstance{Instance: label, Service: service}
+	name := m.names[canonicalName(label+"."+service)]
+
+	srv, srvOK := name.liveSRV(now)
+	if srvOK {
+		inst.Host = srv.target
+		inst.Port = srv.port
+		inst.Addrs = m.names[canonicalName(srv.target)].liveAddrs(now)
+	}
+
+	txt, txtOK := name.liveTXT(now)
+	if txtOK {
+		inst.Text = txt
+	}
+
+	result := ports.ProbeResult{
+		State:    ports.HostDown,
+		Addrs:    inst.Addrs,
+		Service:  &inst,
+		LastSeen: name.seenAt(),
+	}
+
+	if len(inst.Addrs) > 0 {
+		result.Family = familyOf(inst.Addrs[0])
+	}
+
+	if srvOK && (txtOK || !check.RequireTXT) {
+		result.State = ports.HostUp
+	}
+
+	return result, nil
+}
+
+func (m *PassiveMonitor) checkClosed() error {
+	if m.client.Closed() {
+		return &ports.ProbeError{Class: ports.ErrorClassClosed, Err: errors.New("mdns client is closed")}
+	}
+
+	return nil
+}
+
+func (m *PassiveMonitor) observe(msg message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range slices.Concat(msg.Answers, msg.Additionals) {
+		m.observeRecord(r, msg.ReceivedAt)
+	}
+
+	if msg.ReceivedAt.Sub(m.lastPrune) >= pruneInterval {
+		m.prune(msg.ReceivedAt)
+	}
+}
+
+func (m *PassiveMonitor) observeRecord(r dnsmessage.Resource, now time.Time) {
+	key := canonicalName(r.Header.Name.String())
+
+	expires := now.Add(time.Duration(r.Header.TTL) * time.Second)
+	if r.Header.TTL == 0 {
+		expires = now.Add(goodbyeTTL)
+	}
+
+	flush := r.Header.Class&cacheFlushBit != 0
+
+	switch body := r.Body.(type) {
+	case *dnsmessage.AResource:
+		m.name(key, now).observeAddr(netip.AddrFrom4(body.A), now, expires, flush)
+	case *dnsmessage.AAAAResource:
+		m.name(key, now).observeAddr(netip.AddrFrom16(body.AAAA), now, expires, flush)
+	case *dnsmessage.SRVResource:
+		n := m.name(key, now)
+		n.srv = srvRecord{target: trimDot(body.Target.String()), port: body.Port}
+		n.srvExpires = expires
+	case *dnsmessage.TXTResource:
+		n := m.name(key, now)
+		n.txt = nonEmptyTXT(body.TXT)
+		n.txtExpires = expires
+	}
+}
+
+func (m *PassiveMonitor) name(key string, now time.Time) *observedName {
+	n, ok := m.names[key]
+	if !ok {
+		n = &observedName{addrs: make(map[netip.Addr]observedAddr)}
+		m.names[key] = n
+	}
+
+	n.lastSeen = now
+
+	return n
+}
+
+func (m *PassiveMonitor) prune(now time.Time) {
+	m.lastPrune = now
+
+	for key, n := range m.names {
+		for addr, o := range n.addrs {
+			if !o.expires.After(now) {
+				delete(n.addrs, addr)
+			}
+		}
+
+		_, srvOK := n.liveSRV(now)
+		_, txtOK := n.liveTXT(now)
+
+		if !srvOK && !txtOK && len(n.liveAddrs(now)) == 0 && now.Sub(n.lastSeen) > pruneAfter {
+			delete(m.names, key)
+		}
+	}
+}
+
+type observedName struct {
+	lastSeen   time.Time
+	addrs      map[netip.Addr]observedAddr
+	srv        srvRecord
+	srvExpires time.Time
+	txt        []string
+	txtExpires time.Time
+}
+
+type observedAddr struct {
+	seen    time.Time
+	expires time.Time
+}
+
+func (n *observedName) observeAddr(addr netip.Addr, now, expires time.Time, flush bool) {
+	// A cache-flush record replaces the addresses of the same family received more than a second ago.
+	if flush {
+		for a, o := range n.addrs {
+			if a.Is4() == addr.Is4() && now.Sub(o.seen) > goodbyeTTL && o.expires.After(now.Add(goodbyeTTL)) {
+				o.expires = now.Add(goodbyeTTL)
+				n.addrs[a] = o
+			}
+		}
+	}
+
+	n.addrs[addr] = observedAddr{seen: now, expires: expires}
+}
+
+func (n *observedName) seenAt() time.Time {
+	if n == nil {
+		return time.Time{}
+	}
+
+	return n.lastSeen
+}
+
+func (n *observedName) liveAddrs(now time.Time) []netip.Addr {
+	if n == nil {
+		return nil
+	}
+
+	var addrs []netip.Addr
+
+	for addr, o := range n.addrs {
+		if o.expires.After(now) {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	slices.SortFunc(addrs, netip.Addr.Compare)
+
+	return addrs
+}
+
+func (n *observedName) liveSRV(now time.Time) (srvRecord, bool) {
+	if n == nil || !n.srvExpires.After(now) {
+		return srvRecord{}, false
+	}
+
+	return n.srv, true
+}
+
+func (n *observedName) liveTXT(now time.Time) ([]string, bool) {
+	if n == nil || !n.txtExpires.After(now) {
+		return nil, false
+	}
+
+	return n.txt, true
+}
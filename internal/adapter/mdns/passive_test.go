@@ -0,0 +1,163 @@
+package mdns
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var passiveNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func withTTL(r dnsmessage.Resource, ttl uint32) dnsmessage.Resource {
+	r.Header.TTL = ttl
+	return r
+}
+
+func withCacheFlush(r dnsmessage.Resource) dnsmessage.Resource {
+	r.Header.Class |= cacheFlushBit
+	return r
+}
+
+func TestPassiveMonitor_TracksHostsWithinTTL(t *testing.T) {
+	m := newPassiveMonitor(&Client{})
+
+	m.observe(message{
+		ReceivedAt: passiveNow,
+		Message: dnsmessage.Message{
+			Answers: []dnsmessage.Resource{
+				resource("Printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}),
+				withTTL(resource("printer.local.", &dnsmessage.AAAAResource{
+					AAAA: netip.MustParseAddr("fe80::1").As16(),
+				}), 10),
+			},
+		},
+	})
+
+	probeAt := func(d time.Duration) ports.ProbeResult {
+		t.Helper()
+
+		m.now = func() time.Time { return passiveNow.Add(d) }
+
+		result, err := m.Probe(t.Context(), "printer.local", ports.QueryPolicy{Timeout: time.Second})
+		require.NoError(t, err)
+
+		return result
+	}
+
+	require.Equal(t, ports.ProbeResult{
+		State:    ports.HostUp,
+		Addrs:    []netip.Addr{netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("fe80::1")},
+		Family:   ports.FamilyIPv4,
+		LastSeen: passiveNow,
+	}, probeAt(5*time.Second))
+
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.10")}, probeAt(time.Minute).Addrs)
+
+	require.Equal(t, ports.ProbeResult{State: ports.HostDown, LastSeen: passiveNow}, probeAt(2*time.Minute))
+
+	m.now = func() time.Time { return passiveNow }
+
+	result, err := m.Probe(t.Context(), "unknown.local", ports.QueryPolicy{Timeout: time.Second})
+	require.NoError(t, err)
+	require.Equal(t, ports.ProbeResult{State: ports.HostDown}, result)
+}
+
+func TestPassiveMonitor_ExpiresGoodbyeRecords(t *testing.T) {
+	m := newPassiveMonitor(&Client{})
+	m.now = func() time.Time { return passiveNow.Add(2 * time.Second) }
+
+	a := resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}})
+
+	m.observe(message{ReceivedAt: passiveNow, Message: dnsmessage.Message{Answers: []dnsmessage.Resource{a}}})
+	m.observe(message{
+		ReceivedAt: passiveNow.Add(time.Second),
+		Message:    dnsmessage.Message{Answers: []dnsmessage.Resource{withTTL(a, 0)}},
+	})
+
+	result, err := m.Probe(t.Context(), "printer.local", ports.QueryPolicy{Timeout: time.Second})
+	require.NoError(t, err)
+	require.Equal(t, ports.ProbeResult{State: ports.HostDown, LastSeen: passiveNow.Add(time.Second)}, result)
+}
+
+func TestPassiveMonitor_FlushesReplacedAddresses(t *testing.T) {
+	m := newPassiveMonitor(&Client{})
+	m.now = func() time.Time { return passiveNow.Add(5 * time.Second) }
+
+	m.observe(message{ReceivedAt: passiveNow, Message: dnsmessage.Message{Answers: []dnsmessage.Resource{
+		resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}),
+		resource("printer.local.", &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("fe80::1").As16()}),
+	}}})
+	m.observe(message{ReceivedAt: passiveNow.Add(3 * time.Second), Message: dnsmessage.Message{
+		Answers: []dnsmessage.Resource{
+			withCacheFlush(resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}})),
+		},
+	}})
+
+	result, err := m.Probe(t.Context(), "printer.local", ports.QueryPolicy{Timeout: time.Second})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.20"), netip.MustParseAddr("fe80::1")}, result.Addrs)
+}
+
+func TestPassiveMonitor_TracksServiceInstances(t *testing.T) {
+	m := newPassiveMonitor(&Client{})
+	m.now = func() time.Time { return passiveNow }
+
+	m.observe(message{ReceivedAt: passiveNow, Message: dnsmessage.Message{
+		Answers: []dnsmessage.Resource{
+			resource("Office Printer._ipp._tcp.local.", &dnsmessage.SRVResource{
+				Port: 631, Target: dnsmessage.MustNewName("printer.local."),
+			}),
+		},
+		Additionals: []dnsmessage.Resource{
+			resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}),
+		},
+	}})
+
+	check := ports.ServiceCheck{Instance: "office printer._ipp._tcp"}
+
+	result, err := m.ProbeService(t.Context(), check, ports.QueryPolicy{Timeout: time.Second})
+	require.NoError(t, err)
+	require.Equal(t, ports.ProbeResult{
+		State:  ports.HostUp,
+		Addrs:  []netip.Addr{netip.MustParseAddr("192.168.1.10")},
+		Family: ports.FamilyIPv4,
+		Service: &ports.ServiceInstance{
+			Instance: "office printer",
+			Service:  "_ipp._tcp.local",
+			Host:     "printer.local",
+			Port:     631,
+			Addrs:    []netip.Addr{netip.MustParseAddr("192.168.1.10")},
+		},
+		LastSeen: passiveNow,
+	}, result)
+
+	check.RequireTXT = true
+
+	result, err = m.ProbeService(t.Context(), check, ports.QueryPolicy{Timeout: time.Second})
+	require.NoError(t, err)
+	require.Equal(t, ports.HostDown, result.State)
+}
+
+func TestPassiveMonitor_PrunesNamesNotSeenForLong(t *testing.T) {
+	m := newPassiveMonitor(&Client{})
+
+	a := resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}})
+	b := resource("switch.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}})
+
+	m.observe(message{ReceivedAt: passiveNow, Message: dnsmessage.Message{Answers: []dnsmessage.Resource{a}}})
+	m.observe(message{ReceivedAt: passiveNow.Add(30 * time.Minute), Message: dnsmessage.Message{
+		Answers: []dnsmessage.Resource{b},
+	}})
+	require.Len(t, m.names, 2)
+
+	m.observe(message{ReceivedAt: passiveNow.Add(2 * time.Hour), Message: dnsmessage.Message{
+		Answers: []dnsmessage.Resource{b},
+	}})
+	require.Len(t, m.names, 1)
+	require.Contains(t, m.names, "switch.local")
+}
@@ -2,7 +2,8 @@ package mdns
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"net/netip"
 	"time"
 
 	"github.com/khmm12/mdns-health-checker/internal/ports"
@@ -16,32 +17,132 @@ func NewProbe(client *Client) *Probe {
 	return &Probe{client: client}
 }
 
-func (p *Probe) Probe(ctx context.Context, host string, timeout time.Duration) (ports.HostState, error) {
+func (p *Probe) Probe(ctx context.Context, host string, policy ports.QueryPolicy) (ports.ProbeResult, error) {
+	questions, err := p.client.transport.addrQuestions(host)
+	if err != nil {
+		return ports.ProbeResult{}, &ports.ProbeError{
+			Class: ports.ErrorClassOther,
+			Err:   fmt.Errorf("invalid host name %q: %w", host, err),
+		}
+	}
+
 	if err := p.client.sem.Acquire(ctx, 1); err != nil {
-		return ports.HostUnknown, err
+		return ports.ProbeResult{}, &ports.ProbeError{Class: ports.ErrorClassCanceled, Err: err}
 	}
 
 	defer p.client.sem.Release(1)
 
-	innerCtx, cancel := context.WithTimeout(ctx, timeout)
+	msgs, unsubscribe, err := p.client.transport.subscribe()
+	if err != nil {
+		return ports.ProbeResult{}, &ports.ProbeError{Class: p.client.classifyError(ctx), Err: err}
+	}
+
+	defer unsubscribe()
+
+	innerCtx, cancel := context.WithTimeout(ctx, policy.Timeout)
 	defer cancel()
 
-	_, _, err := p.client.conn.QueryAddr(innerCtx, host)
+	q := &hostQuery{
+		answers:  newHostAnswers(host),
+		policy:   policy,
+		schedule: newResendSchedule(policy),
+		send:     func() error { return p.client.transport.query(questions...) },
+	}
+
+	result, err := q.run(innerCtx, msgs)
+
+	// The timeout only ends the query, a canceled parent context fails the probe whatever was received.
+	if ctx.Err() != nil {
+		return ports.ProbeResult{}, &ports.ProbeError{Class: ports.ErrorClassCanceled, Err: ctx.Err()}
+	}
+
 	if err != nil {
-		// If the parent context was canceled due to the deadline error, early return the error as-is.
-		// Helps to distinguish between the parent context being canceled with timeout and the query timing out.
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			return ports.HostUnknown, ctx.Err()
-		}
+		return ports.ProbeResult{}, &ports.ProbeError{Class: p.client.classifyError(ctx), Err: err}
+	}
 
-		// If the query failed due to a timeout, consider the host as down.
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(innerCtx.Err(), context.DeadlineExceeded) {
-			return ports.HostDown, nil
+	return result, nil
+}
+
+// hostQuery queries the address records of a host until it answers or the context is done.
+type hostQuery struct {
+	answers  *hostAnswers
+	policy   ports.QueryPolicy
+	schedule *resendSchedule
+	send     func() error
+
+	sentAt time.Time
+	rtt    time.Duration
+}
+
+func (q *hostQuery) run(ctx context.Context, msgs <-chan message) (ports.ProbeResult, error) {
+	defer q.schedule.stop()
+
+	if err := q.query(); err != nil {
+		return ports.ProbeResult{}, err
+	}
+
+	// window is nil, so it blocks, until the first answer.
+	var window <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return q.result(), nil
+		case <-window:
+			return q.result(), nil
+		case <-q.schedule.due():
+			if err := q.query(); err != nil {
+				return ports.ProbeResult{}, err
+			}
+		case msg, ok := <-msgs:
+			if !ok {
+				return ports.ProbeResult{}, errTransportClosed
+			}
+
+			if q.answers.add(msg) && window == nil {
+				q.rtt = time.Since(q.sentAt)
+				window = time.After(q.policy.AnswerWindow)
+
+				q.schedule.stop()
+			}
 		}
+	}
+}
 
-		// If the query failed for any other reason, return an error.
-		return ports.HostUnknown, err
+func (q *hostQuery) query() error {
+	if err := q.send(); err != nil {
+		return err
 	}
 
-	return ports.HostUp, nil
+	q.schedule.sent()
+	q.sentAt = time.Now()
+
+	return nil
+}
+
+func (q *hostQuery) result() ports.ProbeResult {
+	addrs := q.answers.addrs
+	if len(addrs) == 0 {
+		return ports.ProbeResult{State: ports.HostDown, Attempts: q.schedule.attempts}
+	}
+
+	return ports.ProbeResult{
+		State:    ports.HostUp,
+		RTT:      q.rtt,
+		Addrs:    addrs,
+		Family:   familyOf(addrs[0]),
+		Sources:  q.answers.sources,
+		Attempts: q.schedule.attempts,
+	}
+}
+
+func familyOf(addr netip.Addr) ports.AddrFamily {
+	switch {
+	case addr.Unmap().Is4():
+		return ports.FamilyIPv4
+	case addr.Is6():
+		return ports.FamilyIPv6
+	default:
+		return ports.FamilyUnknown
+	}
 }
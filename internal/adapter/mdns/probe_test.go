@@ -0,0 +1,93 @@
+package mdns
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func TestHostQuery_ResendsUntilTimeout(t *testing.T) {
+	q, sent := newTestHostQuery(ports.QueryPolicy{Attempts: 3, AttemptInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	result, err := q.run(ctx, make(chan message))
+	require.NoError(t, err)
+
+	require.Equal(t, ports.ProbeResult{State: ports.HostDown, Attempts: 3}, result)
+	require.Len(t, *sent, 3)
+
+	// The interval doubles after every query.
+	require.GreaterOrEqual(t, (*sent)[1].Sub((*sent)[0]), 10*time.Millisecond)
+	require.GreaterOrEqual(t, (*sent)[2].Sub((*sent)[1]), 20*time.Millisecond)
+}
+
+func TestHostQuery_SendsSingleQuery(t *testing.T) {
+	q, sent := newTestHostQuery(ports.QueryPolicy{Attempts: 1, AttemptInterval: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := q.run(ctx, make(chan message))
+	require.NoError(t, err)
+
+	require.Equal(t, ports.HostDown, result.State)
+	require.Len(t, *sent, 1)
+}
+
+func TestHostQuery_ReportsAttemptsOfAnswer(t *testing.T) {
+	q, sent := newTestHostQuery(ports.QueryPolicy{
+		Attempts:        3,
+		AttemptInterval: 10 * time.Millisecond,
+		AnswerWindow:    10 * time.Millisecond,
+	})
+
+	msgs := make(chan message, 1)
+
+	// The host answers the second query only.
+	q.send = func() error {
+		*sent = append(*sent, time.Now())
+		if len(*sent) == 2 {
+			msgs <- message{
+				Src: netip.MustParseAddr("192.168.1.10"),
+				Message: dnsmessage.Message{Answers: []dnsmessage.Resource{
+					resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}),
+				}},
+			}
+		}
+
+		return nil
+	}
+
+	result, err := q.run(t.Context(), msgs)
+	require.NoError(t, err)
+
+	require.Equal(t, ports.HostUp, result.State)
+	require.Equal(t, 2, result.Attempts)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.10")}, result.Addrs)
+	require.Equal(t, ports.FamilyIPv4, result.Family)
+	require.Len(t, *sent, 2, "no query is sent after the answer")
+}
+
+func newTestHostQuery(policy ports.QueryPolicy) (*hostQuery, *[]time.Time) {
+	var sent []time.Time
+
+	q := &hostQuery{
+		answers:  newHostAnswers("printer.local"),
+		policy:   policy,
+		schedule: newResendSchedule(policy),
+		send: func() error {
+			sent = append(sent, time.Now())
+			return nil
+		},
+	}
+
+	return q, &sent
+}
@@ -0,0 +1,244 @@
+package mdns
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// servicesMetaQuery is the DNS-SD name enumerating the service types advertised on the network (RFC 6763 §9).
+const servicesMetaQuery = "_services._dns-sd._udp.local"
+
+type srvRecord struct {
+	target string
+	port   uint16
+}
+
+// recordSet accumulates the records of the received responses, keyed by their canonical owner name.
+type recordSet struct {
+	ptr   map[string][]string
+	srv   map[string]srvRecord
+	txt   map[string][]string
+	addrs map[string][]netip.Addr
+}
+
+func newRecordSet() *recordSet {
+	return &recordSet{
+		ptr:   make(map[string][]string),
+		srv:   make(map[string]srvRecord),
+		txt:   make(map[string][]string),
+		addrs: make(map[string][]netip.Addr),
+	}
+}
+
+func (s *recordSet) addMessage(msg dnsmessage.Message) {
+	for _, r := range msg.Answers {
+		s.add(r)
+	}
+
+	for _, r := range msg.Additionals {
+		s.add(r)
+	}
+}
+
+func (s *recordSet) add(r dnsmessage.Resource) {
+	if r.Header.TTL == 0 {
+		return
+	}
+
+	name := canonicalName(r.Header.Name.String())
+
+	switch body := r.Body.(type) {
+	case *dnsmessage.PTRResource:
+		target := trimDot(body.PTR.String())
+		if !slices.ContainsFunc(s.ptr[name], func(t string) bool { return strings.EqualFold(t, target) }) {
+			s.ptr[name] = append(s.ptr[name], target)
+		}
+	case *dnsmessage.SRVResource:
+		s.srv[name] = srvRecord{target: trimDot(body.Target.String()), port: body.Port}
+	case *dnsmessage.TXTResource:
+		s.txt[name] = nonEmptyTXT(body.TXT)
+	case *dnsmessage.AResource:
+		s.addAddr(name, netip.AddrFrom4(body.A))
+	case *dnsmessage.AAAAResource:
+		s.addAddr(name, netip.AddrFrom16(body.AAAA))
+	}
+}
+
+func (s *recordSet) serviceTypes() []string {
+	types := slices.Clone(s.ptr[servicesMetaQuery])
+	slices.SortFunc(types, func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) })
+
+	return types
+}
+
+func (s *recordSet) instances(service string) []ports.ServiceInstance {
+	service = serviceFQDN(service)
+
+	names := s.ptr[canonicalName(service)]
+	out := make([]ports.ServiceInstance, 0, len(names))
+
+	for _, name := range names {
+		inst, _ := s.instance(name, service)
+		out = append(out, inst)
+	}
+
+	slices.SortFunc(out, func(a, b ports.ServiceInstance) int {
+		return strings.Compare(strings.ToLower(a.Instance), strings.ToLower(b.Instance))
+	})
+
+	return out
+}
+
+type instanceRecords struct {
+	ptr bool
+	srv bool
+	txt bool
+}
+
+func (r instanceRecords) complete(requireTXT bool) bool {
+	return r.ptr && r.srv && (r.txt || !requireTXT)
+}
+
+func (s *recordSet) instance(name, service string) (ports.ServiceInstance, instanceRecords) {
+	key := canonicalName(name)
+
+	inst := ports.ServiceInstance{
+		Instance: instanceLabel(trimDot(name), service),
+		Service:  service,
+	}
+
+	var recs instanceRecords
+
+	recs.ptr = slices.ContainsFunc(s.ptr[canonicalName(service)], func(t string) bool {
+		return canonicalName(t) == key
+	})
+
+	if txt, ok := s.txt[key]; ok {
+		recs.txt = true
+		inst.Text = txt
+	}
+
+	if srv, ok := s.srv[key]; ok {
+		recs.srv = true
+		inst.Host = srv.target
+		inst.Port = srv.port
+		inst.Addrs = slices.Clone(s.addrs[canonicalName(srv.target)])
+	}
+
+	return inst, recs
+}
+
+func (s *recordSet) missing(service string) []dnsmessage.Question {
+	var questions []dnsmessage.Question
+
+	for _, name := range s.ptr[canonicalName(serviceFQDN(service))] {
+		questions = append(questions, s.missingInstance(name)...)
+	}
+
+	return questions
+}
+
+func (s *recordSet) missingInstance(name string) []dnsmessage.Question {
+	var questions []dnsmessage.Question
+
+	key := canonicalName(name)
+
+	srv, ok := s.srv[key]
+	if !ok {
+		questions = append(questions, newQuestion(name, dnsmessage.TypeSRV))
+	}
+
+	if _, ok := s.txt[key]; !ok {
+		questions = append(questions, newQuestion(name, dnsmessage.TypeTXT))
+	}
+
+	if ok && len(s.addrs[canonicalName(srv.target)]) == 0 {
+		questions = append(questions,
+			newQuestion(srv.target, dnsmessage.TypeA),
+			newQuestion(srv.target, dnsmessage.TypeAAAA),
+		)
+	}
+
+	return questions
+}
+
+func (s *recordSet) addAddr(name string, addr netip.Addr) {
+	if !slices.Contains(s.addrs[name], addr) {
+		s.addrs[name] = append(s.addrs[name], addr)
+	}
+}
+
+func newQuestion(name string, typ dnsmessage.Type) dnsmessage.Question {
+	return dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(trimDot(name) + "."),
+		Type:  typ,
+		Class: dnsmessage.ClassINET,
+	}
+}
+
+func serviceFQDN(service string) string {
+	service = trimDot(service)
+	if !strings.HasSuffix(strings.ToLower(service), ".local") {
+		service += ".local"
+	}
+
+	return service
+}
+
+func splitInstanceName(name string) (string, string, error) {
+	name = trimDot(name)
+	lower := strings.ToLower(name)
+
+	proto := max(strings.LastIndex(lower, "._tcp"), strings.LastIndex(lower, "._udp"))
+	if proto <= 0 {
+		return "", "", fmt.Errorf("invalid service instance name %q: missing _tcp or _udp service type", name)
+	}
+
+	typ := strings.LastIndex(lower[:proto], "._")
+	if typ <= 0 {
+		return "", "", fmt.Errorf("invalid service instance name %q: missing instance or service name", name)
+	}
+
+	service := serviceFQDN(name[typ+1:])
+	if _, err := dnsmessage.NewName(name[:typ] + "." + service + "."); err != nil {
+		return "", "", fmt.Errorf("invalid service instance name %q: %w", name, err)
+	}
+
+	return name[:typ], service, nil
+}
+
+// Instance labels may contain dots, so the name cannot simply be split on the first one.
+func instanceLabel(name, service string) string {
+	if len(name) > len(service)+1 && strings.EqualFold(name[len(name)-len(service):], service) {
+		return name[:len(name)-len(service)-1]
+	}
+
+	return name
+}
+
+func canonicalName(name string) string {
+	return strings.ToLower(trimDot(name))
+}
+
+func trimDot(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
+
+// nonEmptyTXT drops the empty string a TXT record without any key carries (RFC 6763 §6.1).
+func nonEmptyTXT(txt []string) []string {
+	out := make([]string, 0, len(txt))
+
+	for _, s := range txt {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
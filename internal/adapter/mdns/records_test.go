@@ -0,0 +1,177 @@
+package mdns
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func resource(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: 120},
+		Body:   body,
+	}
+}
+
+func TestRecordSet_ResolvesInstances(t *testing.T) {
+	set := newRecordSet()
+
+	set.addMessage(dnsmessage.Message{
+		Answers: []dnsmessage.Resource{
+			resource("_ipp._tcp.local.", &dnsmessage.PTRResource{
+				PTR: dnsmessage.MustNewName("Office Printer._ipp._tcp.local."),
+			}),
+			resource("_ipp._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Lab v2.1._ipp._tcp.local.")}),
+		},
+		Additionals: []dnsmessage.Resource{
+			resource("office printer._ipp._tcp.local.", &dnsmessage.SRVResource{
+				Port: 631, Target: dnsmessage.MustNewName("printer.local."),
+			}),
+			resource("Office Printer._ipp._tcp.local.", &dnsmessage.TXTResource{TXT: []string{"rp=ipp/print", "ty=Office"}}),
+			resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}),
+			resource("printer.local.", &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("fe80::1").As16()}),
+			resource("printer.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 10}}),
+		},
+	})
+
+	require.Equal(t, []ports.ServiceInstance{
+		{Instance: "Lab v2.1", Service: "_ipp._tcp.local"},
+		{
+			Instance: "Office Printer",
+			Service:  "_ipp._tcp.local",
+			Host:     "printer.local",
+			Port:     631,
+			Text:     []string{"rp=ipp/print", "ty=Office"},
+			Addrs:    []netip.Addr{netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("fe80::1")},
+		},
+	}, set.instances("_ipp._tcp"))
+}
+
+func TestRecordSet_MissingRecords(t *testing.T) {
+	set := newRecordSet()
+
+	set.add(resource("_hap._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Bridge._hap._tcp.local.")}))
+
+	require.Equal(t, []dnsmessage.Question{
+		newQuestion("Bridge._hap._tcp.local", dnsmessage.TypeSRV),
+		newQuestion("Bridge._hap._tcp.local", dnsmessage.TypeTXT),
+	}, set.missing("_hap._tcp.local."))
+
+	set.add(resource("Bridge._hap._tcp.local.", &dnsmessage.SRVResource{
+		Port: 51826, Target: dnsmessage.MustNewName("bridge.local."),
+	}))
+	set.add(resource("Bridge._hap._tcp.local.", &dnsmessage.TXTResource{TXT: []string{""}}))
+
+	require.Equal(t, []dnsmessage.Question{
+		newQuestion("bridge.local", dnsmessage.TypeA),
+		newQuestion("bridge.local", dnsmessage.TypeAAAA),
+	}, set.missing("_hap._tcp"))
+
+	set.add(resource("bridge.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}}))
+
+	require.Empty(t, set.missing("_hap._tcp"))
+	require.Equal(t, []string{}, set.instances("_hap._tcp")[0].Text)
+}
+
+func TestRecordSet_SkipsGoodbyes(t *testing.T) {
+	set := newRecordSet()
+
+	goodbye := func(r dnsmessage.Resource) dnsmessage.Resource {
+		r.Header.TTL = 0
+		return r
+	}
+
+	set.addMessage(dnsmessage.Message{
+		Answers: []dnsmessage.Resource{
+			goodbye(resource("_hap._tcp.local.", &dnsmessage.PTRResource{
+				PTR: dnsmessage.MustNewName("Bridge._hap._tcp.local."),
+			})),
+		},
+		Additionals: []dnsmessage.Resource{
+			goodbye(resource("Bridge._hap._tcp.local.", &dnsmessage.SRVResource{
+				Port: 51826, Target: dnsmessage.MustNewName("bridge.local."),
+			})),
+			goodbye(resource("Bridge._hap._tcp.local.", &dnsmessage.TXTResource{TXT: []string{"sf=0"}})),
+			goodbye(resource("bridge.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}})),
+			goodbye(resource("bridge.local.", &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("fe80::1").As16()})),
+		},
+	})
+
+	inst, recs := set.instance("Bridge._hap._tcp.local", "_hap._tcp.local")
+	require.False(t, recs.complete(false))
+	require.Empty(t, inst.Addrs)
+	require.Empty(t, set.instances("_hap._tcp"))
+	require.Equal(t, []dnsmessage.Question{
+		newQuestion("Bridge._hap._tcp.local", dnsmessage.TypeSRV),
+		newQuestion("Bridge._hap._tcp.local", dnsmessage.TypeTXT),
+	}, set.missingInstance("Bridge._hap._tcp.local"))
+}
+
+func TestRecordSet_ServiceTypes(t *testing.T) {
+	set := newRecordSet()
+
+	set.addMessage(dnsmessage.Message{
+		Answers: []dnsmessage.Resource{
+			resource("_services._dns-sd._udp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_ipp._tcp.local.")}),
+			resource("_services._dns-sd._udp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_hap._tcp.local.")}),
+			resource("_services._dns-sd._udp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_IPP._tcp.local.")}),
+		},
+	})
+
+	require.Equal(t, []string{"_hap._tcp.local", "_ipp._tcp.local"}, set.serviceTypes())
+}
+
+func TestRecordSet_InstanceRecords(t *testing.T) {
+	set := newRecordSet()
+
+	set.add(resource("_hap._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Bridge._hap._tcp.local.")}))
+	set.add(resource("bridge._hap._tcp.local.", &dnsmessage.SRVResource{
+		Port: 51826, Target: dnsmessage.MustNewName("bridge.local."),
+	}))
+
+	inst, recs := set.instance("Bridge._hap._tcp.local", "_hap._tcp.local")
+	require.Equal(t, ports.ServiceInstance{
+		Instance: "Bridge",
+		Service:  "_hap._tcp.local",
+		Host:     "bridge.local",
+		Port:     51826,
+	}, inst)
+	require.True(t, recs.complete(false))
+	require.False(t, recs.complete(true))
+
+	_, recs = set.instance("Other._hap._tcp.local", "_hap._tcp.local")
+	require.False(t, recs.complete(false))
+}
+
+func TestSplitInstanceName(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance string
+		service  string
+		err      string
+	}{
+		{name: "Office Printer._ipp._tcp.local", instance: "Office Printer", service: "_ipp._tcp.local"},
+		{name: "Lab v2.1._ipp._tcp", instance: "Lab v2.1", service: "_ipp._tcp.local"},
+		{name: "Speaker._raop._UDP.local.", instance: "Speaker", service: "_raop._UDP.local"},
+		{name: "printer.local", err: "missing _tcp or _udp service type"},
+		{name: "_ipp._tcp.local", err: "missing instance or service name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, service, err := splitInstanceName(tt.name)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.instance, instance)
+			require.Equal(t, tt.service, service)
+		})
+	}
+}
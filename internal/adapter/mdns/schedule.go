@@ -0,0 +1,39 @@
+package mdns
+
+import (
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// resendSchedule times the queries of a probe on its query policy.
+type resendSchedule struct {
+	policy   ports.QueryPolicy
+	timer    *time.Timer
+	interval time.Duration
+	attempts int
+}
+
+func newResendSchedule(policy ports.QueryPolicy) *resendSchedule {
+	timer := time.NewTimer(0)
+	timer.Stop()
+
+	return &resendSchedule{policy: policy, timer: timer, interval: policy.AttemptInterval}
+}
+
+func (s *resendSchedule) sent() {
+	s.attempts++
+
+	if s.attempts < s.policy.Attempts {
+		s.timer.Reset(s.interval)
+		s.interval *= 2
+	}
+}
+
+func (s *resendSchedule) due() <-chan time.Time {
+	return s.timer.C
+}
+
+func (s *resendSchedule) stop() {
+	s.timer.Stop()
+}
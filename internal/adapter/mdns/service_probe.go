@@ -0,0 +1,80 @@
+package mdns
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+type ServiceProbe struct {
+	client *Client
+}
+
+func NewServiceProbe(client *Client) *ServiceProbe {
+	return &ServiceProbe{client: client}
+}
+
+func (p *ServiceProbe) ProbeService(
+	ctx context.Context,
+	check ports.ServiceCheck,
+	policy ports.QueryPolicy,
+) (ports.ProbeResult, error) {
+	label, service, err := splitInstanceName(check.Instance)
+	if err != nil {
+		return ports.ProbeResult{}, &ports.ProbeError{Class: ports.ErrorClassOther, Err: err}
+	}
+
+	name := label + "." + service
+
+	var rtt time.Duration
+
+	// The instance is asked for directly next to the service type, so responders which only answer the PTR query
+	// without additional records are resolved in the same round trip.
+	q := &lookupQuery{
+		policy: policy,
+		questions: []dnsmessage.Question{
+			newQuestion(service, dnsmessage.TypePTR),
+			newQuestion(name, dnsmessage.TypeSRV),
+			newQuestion(name, dnsmessage.TypeTXT),
+		},
+	}
+
+	q.follow = func(set *recordSet) ([]dnsmessage.Question, bool) {
+		inst, recs := set.instance(name, service)
+
+		if rtt == 0 && recs.complete(check.RequireTXT) {
+			rtt = time.Since(q.sentAt)
+		}
+
+		// Waiting for the addresses too lets the result carry them, but they do not decide the state.
+		return set.missingInstance(name), rtt != 0 && len(inst.Addrs) > 0
+	}
+
+	set, err := p.client.lookup(ctx, q)
+	if err != nil {
+		return ports.ProbeResult{}, &ports.ProbeError{Class: p.client.classifyError(ctx), Err: err}
+	}
+
+	inst, recs := set.instance(name, service)
+
+	result := ports.ProbeResult{
+		State:    ports.HostDown,
+		Addrs:    inst.Addrs,
+		Service:  &inst,
+		Attempts: q.attempts(),
+	}
+
+	if len(inst.Addrs) > 0 {
+		result.Family = familyOf(inst.Addrs[0])
+	}
+
+	if recs.complete(check.RequireTXT) {
+		result.State = ports.HostUp
+		result.RTT = rtt
+	}
+
+	return result, nil
+}
@@ -0,0 +1,380 @@
+package mdns
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+)
+
+const (
+	maxPacketSize    = 9000
+	subscriberBuffer = 64
+	// A failing socket, e.g. of a removed interface, fails every read right away.
+	readBackoffMin = 10 * time.Millisecond
+	readBackoffMax = 5 * time.Second
+)
+
+var (
+	groupAddr4 = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	groupAddr6 = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+)
+
+var errTransportClosed = errors.New("mdns: transport is closed")
+
+type message struct {
+	dnsmessage.Message
+
+	Src        netip.Addr
+	ReceivedAt time.Time
+}
+
+// transport owns the mDNS sockets of a Client and fans the received responses out to its subscribers.
+type transport struct {
+	logger  *slog.Logger
+	conn4   *ipv4.PacketConn
+	conn6   *ipv6.PacketConn
+	ifaces4 []net.Interface
+	ifaces6 []net.Interface
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	subscribers map[chan message]struct{}
+	closed      bool
+	done        chan struct{}
+
+	errMu      sync.Mutex
+	socketErrs map[string]error
+
+	wg sync.WaitGroup
+}
+
+func newTransport(logger *slog.Logger, useIPv4, useIPv6 bool, ipv4Addr, ipv6Addr string) (*transport, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	t := &transport{
+		logger:      logger,
+		subscribers: make(map[chan message]struct{}),
+		done:        make(chan struct{}),
+		socketErrs:  make(map[string]error),
+	}
+
+	if useIPv4 {
+		if t.conn4, err = buildV4Conn(ipv4Addr); err != nil {
+			return nil, err
+		}
+
+		for _, ifc := range multicastInterfaces(ifaces) {
+			if err := t.conn4.JoinGroup(&ifc, groupAddr4); err == nil {
+				t.ifaces4 = append(t.ifaces4, ifc)
+			}
+		}
+	}
+
+	if useIPv6 {
+		if t.conn6, err = buildV6Conn(ipv6Addr); err != nil {
+			_ = t.closeConns()
+			return nil, err
+		}
+
+		for _, ifc := range multicastInterfaces(ifaces) {
+			if err := t.conn6.JoinGroup(&ifc, groupAddr6); err == nil {
+				t.ifaces6 = append(t.ifaces6, ifc)
+			}
+		}
+	}
+
+	if len(t.ifaces4) == 0 && len(t.ifaces6) == 0 {
+		_ = t.closeConns()
+		return nil, errors.New("mdns: failed to join multicast group on any interface")
+	}
+
+	if t.conn4 != nil {
+		t.wg.Go(func() { t.readLoop("ipv4 read", t.read4) })
+	}
+
+	if t.conn6 != nil {
+		t.wg.Go(func() { t.readLoop("ipv6 read", t.read6) })
+	}
+
+	return t, nil
+}
+
+// query multicasts the questions without the unicast-response bit, so every listener observes the answers.
+func (t *transport) query(questions ...dnsmessage.Question) error {
+	msg := dnsmessage.Message{Questions: questions}
+
+	b, err := msg.Pack()
+	if err != nil {
+		return fmt.Errorf("failed to pack mdns query: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	var errs []error
+
+	sent := false
+
+	for _, ifc := range t.ifaces4 {
+		if err := t.conn4.SetMulticastInterface(&ifc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ifc.Name, err))
+			continue
+		}
+
+		if _, err := t.conn4.WriteTo(b, nil, groupAddr4); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ifc.Name, err))
+			continue
+		}
+
+		sent = true
+	}
+
+	for _, ifc := range t.ifaces6 {
+		if err := t.conn6.SetMulticastInterface(&ifc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ifc.Name, err))
+			continue
+		}
+
+		if _, err := t.conn6.WriteTo(b, nil, groupAddr6); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ifc.Name, err))
+			continue
+		}
+
+		sent = true
+	}
+
+	if !sent && len(errs) > 0 {
+		err := fmt.Errorf("failed to send mdns query: %w", errors.Join(errs...))
+		t.setErr("send", err)
+
+		return err
+	}
+
+	t.setErr("send", nil)
+
+	return nil
+}
+
+func (t *transport) addrQuestions(host string) ([]dnsmessage.Question, error) {
+	name, err := dnsmessage.NewName(trimDot(host) + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var questions []dnsmessage.Question
+
+	if len(t.ifaces4) > 0 {
+		questions = append(questions, dnsmessage.Question{
+			Name:  name,
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+
+	if len(t.ifaces6) > 0 {
+		questions = append(questions, dnsmessage.Question{
+			Name:  name,
+			Type:  dnsmessage.TypeAAAA,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+
+	return questions, nil
+}
+
+// subscribe drops the messages a subscriber does not keep up with.
+func (t *transport) subscribe() (<-chan message, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, nil, errTransportClosed
+	}
+
+	ch := make(chan message, subscriberBuffer)
+	t.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+func (t *transport) close() error {
+	t.mu.Lock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+
+	for ch := range t.subscribers {
+		delete(t.subscribers, ch)
+		close(ch)
+	}
+
+	t.mu.Unlock()
+
+	err := t.closeConns()
+
+	t.wg.Wait()
+
+	return err
+}
+
+func (t *transport) closeConns() error {
+	var errs []error
+
+	if t.conn4 != nil {
+		errs = append(errs, t.conn4.Close())
+	}
+
+	if t.conn6 != nil {
+		errs = append(errs, t.conn6.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *transport) err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+
+	errs := make([]error, 0, len(t.socketErrs))
+
+	for _, op := range slices.Sorted(maps.Keys(t.socketErrs)) {
+		errs = append(errs, fmt.Errorf("%s: %w", op, t.socketErrs[op]))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *transport) setErr(op string, err error) {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+
+	if err == nil {
+		delete(t.socketErrs, op)
+		return
+	}
+
+	t.socketErrs[op] = err
+}
+
+func (t *transport) readLoop(op string, read func(b []byte) (int, net.Addr, error)) {
+	b := make([]byte, maxPacketSize)
+
+	var backoff time.Duration
+
+	for {
+		n, src, err := read(b)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			backoff = min(max(2*backoff, readBackoffMin), readBackoffMax)
+
+			t.logger.Debug("Failed to read mdns packet", logging.Error(err), slog.Duration("backoff", backoff))
+			t.setErr(op, err)
+
+			select {
+			case <-t.done:
+				return
+			case <-time.After(backoff):
+			}
+
+			continue
+		}
+
+		backoff = 0
+
+		t.setErr(op, nil)
+
+		var msg dnsmessage.Message
+		if err := msg.Unpack(b[:n]); err != nil {
+			t.logger.Debug("Failed to parse mdns packet", logging.Error(err))
+			continue
+		}
+
+		if !msg.Response {
+			continue
+		}
+
+		t.dispatch(message{Message: msg, Src: udpAddrIP(src), ReceivedAt: time.Now()})
+	}
+}
+
+func (t *transport) dispatch(msg message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			t.logger.Debug("Dropped mdns message for a slow subscriber")
+		}
+	}
+}
+
+func (t *transport) read4(b []byte) (int, net.Addr, error) {
+	n, _, src, err := t.conn4.ReadFrom(b)
+	return n, src, err
+}
+
+func (t *transport) read6(b []byte) (int, net.Addr, error) {
+	n, _, src, err := t.conn6.ReadFrom(b)
+	return n, src, err
+}
+
+func multicastInterfaces(ifaces []net.Interface) []net.Interface {
+	var out []net.Interface
+
+	for _, ifc := range ifaces {
+		if ifc.Flags&net.FlagUp == 0 || ifc.Flags&net.FlagMulticast == 0 || ifc.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		out = append(out, ifc)
+	}
+
+	return out
+}
+
+func udpAddrIP(addr net.Addr) netip.Addr {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return netip.Addr{}
+	}
+
+	ip, ok := netip.AddrFromSlice(udpAddr.IP)
+	if !ok {
+		return netip.Addr{}
+	}
+
+	return ip.Unmap().WithZone(udpAddr.Zone)
+}
@@ -0,0 +1,85 @@
+package mdns
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestTransport_ReportsSocketErrorsUntilRecovered(t *testing.T) {
+	tr := &transport{
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		subscribers: make(map[chan message]struct{}),
+		done:        make(chan struct{}),
+		socketErrs:  make(map[string]error),
+	}
+
+	packet, err := (&dnsmessage.Message{Header: dnsmessage.Header{Response: true}}).Pack()
+	require.NoError(t, err)
+
+	type read struct {
+		packet []byte
+		err    error
+	}
+
+	reads := make(chan read)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		tr.readLoop("ipv4 read", func(b []byte) (int, net.Addr, error) {
+			r := <-reads
+			return copy(b, r.packet), &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10)}, r.err
+		})
+	}()
+
+	reads <- read{err: errors.New("network is down")}
+	reads <- read{err: errors.New("network is down")}
+
+	require.ErrorContains(t, tr.err(), "ipv4 read: network is down")
+
+	reads <- read{packet: packet}
+	reads <- read{packet: packet}
+
+	require.NoError(t, tr.err())
+
+	reads <- read{err: net.ErrClosed}
+	<-done
+}
+
+func TestTransport_BacksOffOnFailingReads(t *testing.T) {
+	tr := &transport{
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		subscribers: make(map[chan message]struct{}),
+		done:        make(chan struct{}),
+		socketErrs:  make(map[string]error),
+	}
+
+	var reads int
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		tr.readLoop("ipv4 read", func([]byte) (int, net.Addr, error) {
+			reads++
+			return 0, nil, errors.New("no buffer space available")
+		})
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, tr.close())
+	<-done
+
+	// The reads are retried after 10ms, 20ms, 40ms and 80ms.
+	require.LessOrEqual(t, reads, 5)
+	require.ErrorContains(t, tr.err(), "ipv4 read: no buffer space available")
+}
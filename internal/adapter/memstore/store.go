@@ -0,0 +1,87 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var (
+	_ ports.MDNSStatePublisher = (*Store)(nil)
+	_ ports.HostStatusReader   = (*Store)(nil)
+)
+
+// Store keeps the last observed status of every checked host in memory.
+// It is fed as a state publisher and read by the status API.
+type Store struct {
+	mu    sync.RWMutex
+	hosts map[string]ports.HostStatus
+}
+
+func New() *Store {
+	return &Store{
+		hosts: make(map[string]ports.HostStatus),
+	}
+}
+
+func (s *Store) Publish(_ context.Context, results []ports.ProbeResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hosts := make(map[string]ports.HostStatus, len(results))
+
+	for _, r := range results {
+		status, ok := s.hosts[r.Host]
+		if !ok || status.State != r.State {
+			status.LastChange = r.CheckedAt
+		}
+
+		if r.State == ports.HostUp {
+			status.LastSuccess = r.CheckedAt
+		}
+
+		status.Host = r.Host
+		status.State = r.State
+		status.LastCheck = r.CheckedAt
+		status.RTT = r.RTT
+		status.Addrs = r.Addrs
+		status.Family = r.Family
+		status.Err = r.Err
+		status.LastSeen = r.LastSeen
+		status.Reason = r.Reason
+
+		hosts[r.Host] = status
+	}
+
+	s.hosts = hosts
+
+	return nil
+}
+
+func (s *Store) Hosts(_ context.Context) []ports.HostStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hosts := make([]ports.HostStatus, 0, len(s.hosts))
+	for _, status := range s.hosts {
+		hosts = append(hosts, status)
+	}
+
+	slices.SortFunc(hosts, func(a, b ports.HostStatus) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	return hosts
+}
+
+func (s *Store) Host(_ context.Context, name string) (ports.HostStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status, ok := s.hosts[name]
+
+	return status, ok
+}
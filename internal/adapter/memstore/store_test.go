@@ -0,0 +1,65 @@
+package memstore
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func TestStore_TracksLastChangeAndSuccess(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	t2 := t1.Add(time.Minute)
+	addr := netip.MustParseAddr("192.168.1.10")
+
+	publish := func(results ...ports.ProbeResult) {
+		t.Helper()
+		require.NoError(t, store.Publish(ctx, results))
+	}
+
+	publish(
+		ports.ProbeResult{Host: "printer.local", CheckedAt: t0, State: ports.HostUp, Addrs: []netip.Addr{addr}},
+		ports.ProbeResult{Host: "switch.local", CheckedAt: t0, State: ports.HostUp},
+	)
+	publish(ports.ProbeResult{Host: "printer.local", CheckedAt: t1, State: ports.HostUp, Addrs: []netip.Addr{addr}})
+	publish(ports.ProbeResult{Host: "printer.local", CheckedAt: t2, State: ports.HostDown})
+
+	status, ok := store.Host(ctx, "printer.local")
+	require.True(t, ok)
+	require.Equal(t, ports.HostStatus{
+		Host:        "printer.local",
+		State:       ports.HostDown,
+		LastCheck:   t2,
+		LastChange:  t2,
+		LastSuccess: t1,
+	}, status)
+
+	_, ok = store.Host(ctx, "switch.local")
+	require.False(t, ok)
+	require.Len(t, store.Hosts(ctx), 1)
+}
+
+func TestStore_ListsHostsByName(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	require.NoError(t, store.Publish(ctx, []ports.ProbeResult{
+		{Host: "switch.local", State: ports.HostUp},
+		{Host: "nas.local", State: ports.HostDown},
+		{Host: "printer.local", State: ports.HostError},
+	}))
+
+	hosts := store.Hosts(ctx)
+	require.Len(t, hosts, 3)
+	require.Equal(t, "nas.local", hosts[0].Host)
+	require.Equal(t, "printer.local", hosts[1].Host)
+	require.Equal(t, "switch.local", hosts[2].Host)
+}
@@ -0,0 +1,154 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+)
+
+// Payloads of the availability topic of the checker.
+const (
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+// qos is the quality of service of every message. Messages are retained, so they must not get lost.
+const qos = 1
+
+// Intervals between the attempts to connect to the broker, the reconnect interval doubles up to its maximum.
+const (
+	connectRetryInterval = 5 * time.Second
+	maxReconnectInterval = time.Minute
+)
+
+// disconnectQuiesceMillis is how long a closing client waits for the outstanding messages to be sent.
+const disconnectQuiesceMillis = 250
+
+var (
+	errNotConnected   = errors.New("not connected to the broker")
+	errPublishTimeout = errors.New("timed out waiting for the broker")
+)
+
+type ClientOptions struct {
+	// URL is the address of the broker, e.g. "tcp://broker:1883" or "ssl://broker:8883".
+	URL      string
+	ClientID string
+	Username string
+	Password string
+	// StatusTopic is the availability topic of the checker. It is "online" while the client is connected and
+	// "offline" otherwise, set by the last will if the connection is lost.
+	StatusTopic string
+	// Timeout bounds the initial connection and every publication.
+	Timeout time.Duration
+}
+
+// Client is a connection to an MQTT broker which reconnects on its own.
+type Client struct {
+	logger *slog.Logger
+	client paho.Client
+	opts   ClientOptions
+
+	mu        sync.Mutex
+	onConnect []func()
+}
+
+// Connect connects to the broker. If the broker cannot be reached within the timeout, the client keeps connecting
+// in the background and the messages published meanwhile fail.
+func Connect(ctx context.Context, logger *slog.Logger, opts ClientOptions) (*Client, error) {
+	c := &Client{
+		logger: logger,
+		opts:   opts,
+	}
+
+	pahoOpts := paho.NewClientOptions().
+		AddBroker(opts.URL).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetConnectTimeout(opts.Timeout).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(connectRetryInterval).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(maxReconnectInterval).
+		SetWill(opts.StatusTopic, payloadOffline, qos, true).
+		SetOnConnectHandler(func(paho.Client) { c.connected(ctx) }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.WarnContext(ctx, "Lost connection to MQTT broker", logging.Error(err))
+		})
+
+	c.client = paho.NewClient(pahoOpts)
+
+	token := c.client.Connect()
+	if !token.WaitTimeout(opts.Timeout) {
+		logger.WarnContext(ctx, "Failed to connect to MQTT broker in time, retrying in the background",
+			slog.String("url", opts.URL),
+		)
+
+		return c, nil
+	}
+
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("failed to connect to mqtt broker: %w", err)
+	}
+
+	return c, nil
+}
+
+// OnConnect makes fn be called whenever the client reconnects. A broker may have lost the retained messages
+// meanwhile.
+func (c *Client) OnConnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onConnect = append(c.onConnect, fn)
+}
+
+// Publish publishes the payload to the topic and waits until the broker received it. It fails at once while the
+// client is not connected rather than queueing the message, the publisher resyncs after reconnecting.
+func (c *Client) Publish(topic string, payload []byte, retained bool) error {
+	if !c.client.IsConnectionOpen() {
+		return fmt.Errorf("failed to publish to %s: %w", topic, errNotConnected)
+	}
+
+	token := c.client.Publish(topic, qos, retained, payload)
+	if !token.WaitTimeout(c.opts.Timeout) {
+		return fmt.Errorf("failed to publish to %s: %w", topic, errPublishTimeout)
+	}
+
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+
+	return nil
+}
+
+// Close marks the checker offline and disconnects.
+func (c *Client) Close() error {
+	err := c.Publish(c.opts.StatusTopic, []byte(payloadOffline), true)
+
+	c.client.Disconnect(disconnectQuiesceMillis)
+
+	return err
+}
+
+func (c *Client) connected(ctx context.Context) {
+	c.logger.InfoContext(ctx, "Connected to MQTT broker", slog.String("url", c.opts.URL))
+
+	// The status is not waited for, the client sends it as soon as the connection is ready.
+	c.client.Publish(c.opts.StatusTopic, qos, true, payloadOnline)
+
+	c.mu.Lock()
+	handlers := c.onConnect
+	c.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn()
+	}
+}
@@ -0,0 +1,256 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var _ ports.MDNSStatePublisher = (*Publisher)(nil)
+
+// Broker publishes messages to an MQTT broker, see Client.
+type Broker interface {
+	Publish(topic string, payload []byte, retained bool) error
+}
+
+// StatusTopic returns the availability topic of the checker, see ClientOptions.StatusTopic.
+func StatusTopic(prefix string) string {
+	return prefix + "/status"
+}
+
+type PublisherOptions struct {
+	// TopicPrefix is the first level of every topic of the checker, e.g. "mdns-health-checker".
+	TopicPrefix string
+	// DiscoveryPrefix is the discovery prefix of Home Assistant, e.g. "homeassistant". Empty disables the discovery.
+	DiscoveryPrefix string
+}
+
+// Publisher publishes the state, round-trip time and addresses of every host to retained topics, and announces the
+// hosts to Home Assistant as connectivity binary sensors.
+//
+// A message is only published when its payload changed, the broker retains the previous one.
+type Publisher struct {
+	logger *slog.Logger
+	broker Broker
+	opts   PublisherOptions
+
+	mu sync.Mutex
+	// published are the payloads last published by topic.
+	published map[string]string
+	// ids are the object IDs of the hosts published by the previous Publish call, by host.
+	ids map[string]string
+}
+
+func NewPublisher(logger *slog.Logger, broker Broker, opts PublisherOptions) *Publisher {
+	return &Publisher{
+		logger:    logger,
+		broker:    broker,
+		opts:      opts,
+		published: make(map[string]string),
+		ids:       make(map[string]string),
+	}
+}
+
+// Resync makes the next Publish call publish every message again, e.g. after the broker restarted and lost the
+// retained ones.
+func (p *Publisher) Resync() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clear(p.published)
+}
+
+func (p *Publisher) Publish(ctx context.Context, results []ports.ProbeResult) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+
+	hosts := make([]string, 0, len(results))
+	for _, r := range results {
+		hosts = append(hosts, r.Host)
+	}
+
+	ids := objectIDs(hosts)
+
+	// The topics of removed hosts, and of hosts whose object ID changed, are stale.
+	for host, id := range p.ids {
+		if ids[host] == id {
+			continue
+		}
+
+		p.logger.DebugContext(ctx, "Removing stale mqtt topics of host", slog.String("host", host))
+
+		// An empty retained message deletes the retained one, and the entity in Home Assistant.
+		for _, topic := range p.hostTopics(id) {
+			if err := p.publish(topic, ""); err != nil {
+				errs = append(errs, err)
+			}
+
+			delete(p.published, topic)
+		}
+	}
+
+	for _, r := range results {
+		for _, msg := range p.hostMessages(r, ids[r.Host]) {
+			if err := p.publish(msg.topic, msg.payload); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	p.ids = ids
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish to mqtt: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+type message struct {
+	topic   string
+	payload string
+}
+
+// hostMessages returns the messages describing the host with the object ID. The round-trip time is empty unless the
+// host answered, and the addresses are the last-known ones, kept while the host does not answer.
+func (p *Publisher) hostMessages(r ports.ProbeResult, id string) []message {
+	var rtt string
+	if r.State.Answered() && r.RTT > 0 {
+		rtt = strconv.FormatFloat(r.RTT.Seconds(), 'f', -1, 64)
+	}
+
+	addrs := make([]string, 0, len(r.KnownAddrs))
+	for _, addr := range r.KnownAddrs {
+		addrs = append(addrs, addr.String())
+	}
+
+	msgs := []message{
+		{topic: p.hostTopic(id, "state"), payload: r.State.String()},
+		{topic: p.hostTopic(id, "rtt"), payload: rtt},
+		{topic: p.hostTopic(id, "address"), payload: strings.Join(addrs, ",")},
+	}
+
+	if p.opts.DiscoveryPrefix != "" {
+		msgs = append(msgs, message{topic: p.discoveryTopic(id), payload: p.discoveryConfig(r.Host, id)})
+	}
+
+	return msgs
+}
+
+// hostTopics returns every topic of the host with the object ID.
+func (p *Publisher) hostTopics(id string) []string {
+	topics := []string{p.hostTopic(id, "state"), p.hostTopic(id, "rtt"), p.hostTopic(id, "address")}
+
+	if p.opts.DiscoveryPrefix != "" {
+		topics = append(topics, p.discoveryTopic(id))
+	}
+
+	return topics
+}
+
+func (p *Publisher) publish(topic, payload string) error {
+	if prev, ok := p.published[topic]; ok && prev == payload {
+		return nil
+	}
+
+	if err := p.broker.Publish(topic, []byte(payload), true); err != nil {
+		return err
+	}
+
+	p.published[topic] = payload
+
+	return nil
+}
+
+func (p *Publisher) hostTopic(id, name string) string {
+	return p.opts.TopicPrefix + "/hosts/" + id + "/" + name
+}
+
+func (p *Publisher) discoveryTopic(id string) string {
+	return p.opts.DiscoveryPrefix + "/binary_sensor/" + p.nodeID() + "/" + id + "/config"
+}
+
+// discoveryConfig returns the Home Assistant discovery config of the host: a connectivity binary sensor which is on
+// while the host is up, and unavailable while the checker is offline.
+func (p *Publisher) discoveryConfig(host, id string) string {
+	node := p.nodeID()
+
+	config := map[string]any{
+		"name":                  host,
+		"unique_id":             node + "_" + id,
+		"device_class":          "connectivity",
+		"state_topic":           p.hostTopic(id, "state"),
+		"value_template":        "{{ 'ON' if value == 'up' else 'OFF' }}",
+		"availability_topic":    StatusTopic(p.opts.TopicPrefix),
+		"payload_available":     payloadOnline,
+		"payload_not_available": payloadOffline,
+		"device": map[string]any{
+			"identifiers": []string{node},
+			"name":        "mDNS Health Checker",
+			"model":       "mdns-health-checker",
+		},
+	}
+
+	// A map of strings and string slices always encodes.
+	b, _ := json.Marshal(config)
+
+	return string(b)
+}
+
+// nodeID returns the node ID of the checker in Home Assistant, telling several checkers apart by their prefix.
+func (p *Publisher) nodeID() string {
+	return objectID(p.opts.TopicPrefix)
+}
+
+// objectIDs returns the object IDs of the hosts by host. A host's ID is its objectID, suffixed with a hash of its name
+// if the objectID of another host is the same, e.g. of "my.host.local" and "my_host.local".
+func objectIDs(hosts []string) map[string]string {
+	byID := make(map[string][]string, len(hosts))
+	for _, host := range hosts {
+		id := objectID(host)
+		byID[id] = append(byID[id], host)
+	}
+
+	ids := make(map[string]string, len(hosts))
+
+	for id, names := range byID {
+		for _, host := range names {
+			if len(names) == 1 {
+				ids[host] = id
+				continue
+			}
+
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(host))
+
+			ids[host] = fmt.Sprintf("%s_%08x", id, h.Sum32())
+		}
+	}
+
+	return ids
+}
+
+// objectID returns the name with every character outside of [a-z0-9_-] replaced by an underscore, as Home Assistant
+// requires of the IDs in discovery topics.
+func objectID(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, strings.TrimSuffix(name, "."))
+}
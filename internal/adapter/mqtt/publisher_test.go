@@ -0,0 +1,171 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+type fakeBroker struct {
+	// retained are the retained payloads by topic.
+	retained map[string]string
+	// count is the number of publications.
+	count int
+	err   error
+}
+
+func (b *fakeBroker) Publish(topic string, payload []byte, retained bool) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	b.count++
+
+	if retained && len(payload) == 0 {
+		delete(b.retained, topic)
+	} else if retained {
+		b.retained[topic] = string(payload)
+	}
+
+	return nil
+}
+
+func TestPublisher_PublishesHostTopics(t *testing.T) {
+	ctx := context.Background()
+	broker, publisher := newTestPublisher(t)
+
+	addr := netip.MustParseAddr("192.168.1.10")
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer.local", State: ports.HostUp, RTT: 25 * time.Millisecond, KnownAddrs: []netip.Addr{addr}},
+		{Host: "Office Printer._ipp._tcp.local", State: ports.HostDown},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "up", broker.retained["mdns/hosts/printer_local/state"])
+	require.Equal(t, "0.025", broker.retained["mdns/hosts/printer_local/rtt"])
+	require.Equal(t, "192.168.1.10", broker.retained["mdns/hosts/printer_local/address"])
+	require.Equal(t, "down", broker.retained["mdns/hosts/office_printer__ipp__tcp_local/state"])
+
+	var config map[string]any
+
+	raw := broker.retained["homeassistant/binary_sensor/mdns/printer_local/config"]
+	require.NoError(t, json.Unmarshal([]byte(raw), &config))
+	require.Equal(t, "printer.local", config["name"])
+	require.Equal(t, "mdns_printer_local", config["unique_id"])
+	require.Equal(t, "connectivity", config["device_class"])
+	require.Equal(t, "mdns/hosts/printer_local/state", config["state_topic"])
+	require.Equal(t, "mdns/status", config["availability_topic"])
+}
+
+func TestPublisher_SuffixesCollidingHosts(t *testing.T) {
+	ctx := context.Background()
+	broker, publisher := newTestPublisher(t)
+
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "my.host.local", State: ports.HostUp},
+		{Host: "my_host.local", State: ports.HostDown},
+		{Host: "printer.local", State: ports.HostUp},
+	}))
+
+	require.Len(t, broker.retained, 6)
+	require.Equal(t, "up", broker.retained["mdns/hosts/my_host_local_1d942830/state"])
+	require.Equal(t, "down", broker.retained["mdns/hosts/my_host_local_941f50e3/state"])
+	require.Equal(t, "up", broker.retained["mdns/hosts/printer_local/state"])
+	require.Contains(t, broker.retained, "homeassistant/binary_sensor/mdns/my_host_local_1d942830/config")
+	require.Contains(t, broker.retained, "homeassistant/binary_sensor/mdns/my_host_local_941f50e3/config")
+
+	// Without the colliding host the suffix is dropped, and the suffixed topics are removed.
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "my_host.local", State: ports.HostDown},
+		{Host: "printer.local", State: ports.HostUp},
+	}))
+
+	require.Len(t, broker.retained, 4)
+	require.Equal(t, "down", broker.retained["mdns/hosts/my_host_local/state"])
+	require.Contains(t, broker.retained, "homeassistant/binary_sensor/mdns/my_host_local/config")
+}
+
+func TestPublisher_PublishesOnlyChanges(t *testing.T) {
+	ctx := context.Background()
+	broker, publisher := newTestPublisher(t)
+
+	addr := netip.MustParseAddr("192.168.1.10")
+
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer.local", State: ports.HostUp, RTT: 25 * time.Millisecond, KnownAddrs: []netip.Addr{addr}},
+	}))
+	require.Equal(t, 4, broker.count)
+
+	// The round-trip time is cleared while the addresses are kept.
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer.local", State: ports.HostDown, KnownAddrs: []netip.Addr{addr}},
+	}))
+	require.Equal(t, 6, broker.count)
+	require.Equal(t, "down", broker.retained["mdns/hosts/printer_local/state"])
+	require.NotContains(t, broker.retained, "mdns/hosts/printer_local/rtt")
+	require.Equal(t, "192.168.1.10", broker.retained["mdns/hosts/printer_local/address"])
+
+	publisher.Resync()
+
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer.local", State: ports.HostDown, KnownAddrs: []netip.Addr{addr}},
+	}))
+	require.Equal(t, 10, broker.count)
+}
+
+func TestPublisher_RemovesStaleHosts(t *testing.T) {
+	ctx := context.Background()
+	broker, publisher := newTestPublisher(t)
+
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer.local", State: ports.HostUp},
+		{Host: "switch.local", State: ports.HostUp},
+	}))
+
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "switch.local", State: ports.HostUp},
+	}))
+
+	require.Len(t, broker.retained, 2)
+	require.Equal(t, "up", broker.retained["mdns/hosts/switch_local/state"])
+	require.Contains(t, broker.retained, "homeassistant/binary_sensor/mdns/switch_local/config")
+}
+
+func TestPublisher_RetriesFailedPublications(t *testing.T) {
+	ctx := context.Background()
+	broker, publisher := newTestPublisher(t)
+
+	results := []ports.ProbeResult{{Host: "printer.local", State: ports.HostUp}}
+
+	broker.err = errors.New("not connected")
+
+	err := publisher.Publish(ctx, results)
+	require.ErrorContains(t, err, "failed to publish to mqtt: not connected")
+
+	broker.err = nil
+
+	require.NoError(t, publisher.Publish(ctx, results))
+	require.Equal(t, "up", broker.retained["mdns/hosts/printer_local/state"])
+}
+
+func newTestPublisher(t *testing.T) (*fakeBroker, *Publisher) {
+	t.Helper()
+
+	broker := &fakeBroker{retained: make(map[string]string)}
+	publisher := NewPublisher(slog.New(slog.NewTextHandler(io.Discard, nil)), broker, PublisherOptions{
+		TopicPrefix:     "mdns",
+		DiscoveryPrefix: "homeassistant",
+	})
+
+	return broker, publisher
+}
@@ -12,10 +12,15 @@ type Exporter struct {
 	metrics *metrics
 }
 
-func NewExporter() (*Exporter, error) {
+type ExporterOptions struct {
+	// NativeHistograms enables native histograms in addition to the classic buckets.
+	NativeHistograms bool
+}
+
+func NewExporter(opts ExporterOptions) (*Exporter, error) {
 	reg := prometheus.NewRegistry()
 
-	metrics, err := newMetrics(reg)
+	metrics, err := newMetrics(reg, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -29,3 +34,9 @@ func NewExporter() (*Exporter, error) {
 func (e *Exporter) Handler() http.Handler {
 	return promhttp.HandlerFor(e.reg, promhttp.HandlerOpts{})
 }
+
+// SetHostLabels replaces the user-defined labels exported for each host.
+// Every host present in the map is exported, even without labels.
+func (e *Exporter) SetHostLabels(labels map[string]map[string]string) {
+	e.metrics.hostInfo.set(labels)
+}
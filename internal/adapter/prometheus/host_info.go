@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hostInfoCollector exports the user-defined labels of every host as a single info metric.
+//
+// Label names depend on the configuration, so the collector is unchecked and builds its descriptor on each
+// collection. Hosts missing a label which is defined for another host export it with an empty value.
+type hostInfoCollector struct {
+	mu     sync.RWMutex
+	labels map[string]map[string]string
+}
+
+func newHostInfoCollector() *hostInfoCollector {
+	return &hostInfoCollector{
+		labels: make(map[string]map[string]string),
+	}
+}
+
+func (c *hostInfoCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *hostInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.labels) == 0 {
+		return
+	}
+
+	names := make(map[string]struct{})
+	for _, labels := range c.labels {
+		for name := range labels {
+			names[name] = struct{}{}
+		}
+	}
+
+	labelNames := slices.Sorted(maps.Keys(names))
+
+	desc := prometheus.NewDesc(
+		prefix+"host_info",
+		"User-defined labels of a specific host, always 1",
+		append([]string{"host"}, labelNames...),
+		nil,
+	)
+
+	for _, host := range slices.Sorted(maps.Keys(c.labels)) {
+		values := make([]string, 0, len(labelNames)+1)
+		values = append(values, host)
+
+		for _, name := range labelNames {
+			values = append(values, c.labels[host][name])
+		}
+
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, values...)
+	}
+}
+
+func (c *hostInfoCollector) set(labels map[string]map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.labels = maps.Clone(labels)
+}
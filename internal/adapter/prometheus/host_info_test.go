@@ -0,0 +1,37 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_SetHostLabelsExportsUnionOfLabels(t *testing.T) {
+	exporter, err := NewExporter(ExporterOptions{})
+	require.NoError(t, err)
+
+	exporter.SetHostLabels(map[string]map[string]string{
+		"printer.local": {"room": "office", "vendor": "brother"},
+		"switch.local":  {"room": "rack"},
+		"nas.local":     nil,
+	})
+
+	expected := `
+# HELP mdns_host_info User-defined labels of a specific host, always 1
+# TYPE mdns_host_info gauge
+mdns_host_info{host="nas.local",room="",vendor=""} 1
+mdns_host_info{host="printer.local",room="office",vendor="brother"} 1
+mdns_host_info{host="switch.local",room="rack",vendor=""} 1
+`
+
+	err = testutil.GatherAndCompare(exporter.reg, strings.NewReader(expected), prefix+"host_info")
+	require.NoError(t, err)
+
+	exporter.SetHostLabels(nil)
+
+	count, err := testutil.GatherAndCount(exporter.reg, prefix+"host_info")
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -3,52 +3,196 @@ package prometheus
 import (
 	"context"
 	"log/slog"
+	"net/netip"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
 )
 
+var hostStates = []ports.HostState{
+	ports.HostUp,
+	ports.HostDown,
+	ports.HostError,
+	ports.HostDegraded,
+	ports.HostMismatch,
+	ports.HostFlapping,
+}
+
 type MDNSStatePublisher struct {
 	logger   *slog.Logger
 	exporter *Exporter
+
+	mu sync.Mutex
+	// hosts are the hosts exported by the previous Publish call.
+	hosts map[string]struct{}
 }
 
 func NewMDNSStatePublisher(logger *slog.Logger, exporter *Exporter) *MDNSStatePublisher {
 	return &MDNSStatePublisher{
 		logger:   logger,
 		exporter: exporter,
+		hosts:    make(map[string]struct{}),
 	}
 }
 
-func (p *MDNSStatePublisher) Publish(ctx context.Context, up, down []string) error {
+func (p *MDNSStatePublisher) Publish(ctx context.Context, results []ports.ProbeResult) error {
+	var up, down, errored, degraded, mismatch, flapping int
+
+	for _, r := range results {
+		switch r.State {
+		case ports.HostUp:
+			up++
+		case ports.HostDown:
+			down++
+		case ports.HostDegraded:
+			degraded++
+		case ports.HostMismatch:
+			mismatch++
+		case ports.HostFlapping:
+			flapping++
+		case ports.HostError, ports.HostUnknown:
+			errored++
+		}
+	}
+
 	p.logger.DebugContext(ctx, "Publishing mdns check results",
 		slog.Group("publish",
-			slog.Int("up_hosts", len(up)),
-			slog.Int("down_hosts", len(down)),
+			slog.Int("up_hosts", up),
+			slog.Int("down_hosts", down),
+			slog.Int("error_hosts", errored),
+			slog.Int("degraded_hosts", degraded),
+			slog.Int("mismatch_hosts", mismatch),
+			slog.Int("flapping_hosts", flapping),
 		))
 
-	total := len(up) + len(down)
-	if total == 0 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.removeStaleHosts(ctx, results)
+
+	if len(results) == 0 {
 		p.logger.DebugContext(ctx, "No hosts found for mdns check")
 		return nil
 	}
 
+	// A degraded or mismatched host still answers, so it proves the network works.
 	var status float64
-	if len(up) > 0 {
+	if up+degraded+mismatch > 0 {
 		status = 1.0
 	}
 
 	m := p.exporter.metrics
 
 	m.networkStatus.Set(status)
-	m.networkHostsTotal.Set(float64(len(up) + len(down)))
-	m.networkHostsUp.Set(float64(len(up)))
-	m.networkHostsDown.Set(float64(len(down)))
+	m.networkHostsTotal.Set(float64(len(results)))
+	m.networkHostsUp.Set(float64(up))
+	m.networkHostsDown.Set(float64(down))
+	m.networkHostsError.Set(float64(errored))
+	m.networkHostsDegraded.Set(float64(degraded))
+	m.networkHostsMismatch.Set(float64(mismatch))
+	m.networkHostsFlapping.Set(float64(flapping))
 
-	for _, host := range up {
-		m.networkHostStatus.WithLabelValues(host).Set(1.0)
-	}
+	for _, r := range results {
+		var hostStatus float64
+		if r.State == ports.HostUp {
+			hostStatus = 1.0
+		}
+
+		m.networkHostStatus.WithLabelValues(r.Host).Set(hostStatus)
+
+		// Passively monitored hosts answer no query, so they have neither round-trip time nor attempts. A reused
+		// result was observed when it was probed.
+		if r.State.Answered() && !r.Reused && r.RTT > 0 {
+			m.probeDuration.WithLabelValues(r.Host).Observe(r.RTT.Seconds())
+		}
+
+		if r.State.Answered() && !r.Reused && r.Attempts > 0 {
+			m.probeAttempts.WithLabelValues(r.Host).Observe(float64(r.Attempts))
+		}
+
+		// Only the current reason is exported, so a previous one is removed when the reason changes.
+		m.hostDegraded.DeletePartialMatch(prometheus.Labels{"host": r.Host})
+		m.hostMismatch.DeletePartialMatch(prometheus.Labels{"host": r.Host})
+
+		if r.State == ports.HostDegraded {
+			m.hostDegraded.WithLabelValues(r.Host, r.Reason).Set(1)
+		}
+
+		if r.State == ports.HostMismatch {
+			m.hostMismatch.WithLabelValues(r.Host, r.Reason).Set(1)
+		}
+
+		var conflict float64
+		if r.Conflict {
+			conflict = 1.0
+		}
+
+		m.hostConflict.WithLabelValues(r.Host).Set(conflict)
 
-	for _, host := range down {
-		m.networkHostStatus.WithLabelValues(host).Set(0.0)
+		if r.AddrChange != nil {
+			m.hostAddrChanges.WithLabelValues(r.Host).Inc()
+		}
+
+		if r.Flapped {
+			m.hostFlaps.WithLabelValues(r.Host).Inc()
+		}
+
+		m.hostAddrInfo.DeletePartialMatch(prometheus.Labels{"host": r.Host})
+
+		for _, addr := range r.KnownAddrs {
+			m.hostAddrInfo.WithLabelValues(r.Host, addrFamily(addr).String(), addr.String()).Set(1)
+		}
+
+		for _, s := range hostStates {
+			var v float64
+			if s == r.State {
+				v = 1.0
+			}
+
+			m.networkHostState.WithLabelValues(r.Host, s.String()).Set(v)
+		}
 	}
 
 	return nil
 }
+
+// removeStaleHosts deletes the per-host series of hosts which are no longer probed.
+func (p *MDNSStatePublisher) removeStaleHosts(ctx context.Context, results []ports.ProbeResult) {
+	current := make(map[string]struct{}, len(results))
+	for _, r := range results {
+		current[r.Host] = struct{}{}
+	}
+
+	m := p.exporter.metrics
+
+	for host := range p.hosts {
+		if _, ok := current[host]; ok {
+			continue
+		}
+
+		p.logger.DebugContext(ctx, "Removing metrics of stale host", slog.String("host", host))
+
+		m.networkHostStatus.DeleteLabelValues(host)
+		m.networkHostState.DeletePartialMatch(prometheus.Labels{"host": host})
+		m.hostDegraded.DeletePartialMatch(prometheus.Labels{"host": host})
+		m.hostMismatch.DeletePartialMatch(prometheus.Labels{"host": host})
+		m.hostConflict.DeleteLabelValues(host)
+		m.hostAddrChanges.DeleteLabelValues(host)
+		m.hostFlaps.DeleteLabelValues(host)
+		m.hostAddrInfo.DeletePartialMatch(prometheus.Labels{"host": host})
+		m.probeDuration.DeleteLabelValues(host)
+		m.probeAttempts.DeleteLabelValues(host)
+	}
+
+	p.hosts = current
+}
+
+func addrFamily(addr netip.Addr) ports.AddrFamily {
+	if addr.Unmap().Is4() {
+		return ports.FamilyIPv4
+	}
+
+	return ports.FamilyIPv6
+}
@@ -2,36 +2,50 @@ package prometheus
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
 )
 
 func TestMDNSStatePublisher_PublishMetricsForUpAndDownHosts(t *testing.T) {
 	ctx := context.Background()
 	exporter, publisher := newTestPublisher(t)
 
-	err := publisher.Publish(ctx, []string{"host-up"}, []string{"host-down-1", "host-down-2"})
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "host-up", State: ports.HostUp, RTT: 30 * time.Millisecond},
+		{Host: "host-down-1", State: ports.HostDown},
+		{Host: "host-down-2", State: ports.HostDown},
+	})
 	require.NoError(t, err)
 
 	requireMetric(t, 1.0, exporter.metrics.networkStatus)
 	requireMetric(t, 3.0, exporter.metrics.networkHostsTotal)
 	requireMetric(t, 1.0, exporter.metrics.networkHostsUp)
 	requireMetric(t, 2.0, exporter.metrics.networkHostsDown)
+	requireMetric(t, 0.0, exporter.metrics.networkHostsError)
 	requireMetric(t, 1.0, exporter.metrics.networkHostStatus.WithLabelValues("host-up"))
 	requireMetric(t, 0.0, exporter.metrics.networkHostStatus.WithLabelValues("host-down-1"))
 	requireMetric(t, 0.0, exporter.metrics.networkHostStatus.WithLabelValues("host-down-2"))
+
+	requireHistogram(t, exporter, "host-up", 1, 0.03)
 }
 
 func TestMDNSStatePublisher_PublishFailureWhenAllDown(t *testing.T) {
 	ctx := context.Background()
 	exporter, publisher := newTestPublisher(t)
 
-	err := publisher.Publish(ctx, nil, []string{"host-down"})
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "host-down", State: ports.HostDown},
+	})
 	require.NoError(t, err)
 
 	requireMetric(t, 0.0, exporter.metrics.networkStatus)
@@ -45,7 +59,7 @@ func TestMDNSStatePublisher_PublishNoHostsNoop(t *testing.T) {
 	ctx := context.Background()
 	exporter, publisher := newTestPublisher(t)
 
-	err := publisher.Publish(ctx, nil, nil)
+	err := publisher.Publish(ctx, nil)
 	require.NoError(t, err)
 
 	requireMetric(t, 0.0, exporter.metrics.networkStatus)
@@ -54,10 +68,225 @@ func TestMDNSStatePublisher_PublishNoHostsNoop(t *testing.T) {
 	requireMetric(t, 0.0, exporter.metrics.networkHostsDown)
 }
 
+func TestMDNSStatePublisher_PublishErrorState(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "host-up", State: ports.HostUp},
+		{Host: "host-error", State: ports.HostError, Err: errors.New("socket closed")},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 1.0, exporter.metrics.networkStatus)
+	requireMetric(t, 2.0, exporter.metrics.networkHostsTotal)
+	requireMetric(t, 1.0, exporter.metrics.networkHostsUp)
+	requireMetric(t, 0.0, exporter.metrics.networkHostsDown)
+	requireMetric(t, 1.0, exporter.metrics.networkHostsError)
+	requireMetric(t, 0.0, exporter.metrics.networkHostStatus.WithLabelValues("host-error"))
+	requireMetric(t, 1.0, exporter.metrics.networkHostState.WithLabelValues("host-error", "error"))
+	requireMetric(t, 0.0, exporter.metrics.networkHostState.WithLabelValues("host-error", "down"))
+	requireMetric(t, 1.0, exporter.metrics.networkHostState.WithLabelValues("host-up", "up"))
+}
+
+func TestMDNSStatePublisher_PublishDegradedState(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "bridge", State: ports.HostDegraded, RTT: 20 * time.Millisecond, Reason: "txt_value_mismatch:sf"},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 1.0, exporter.metrics.networkStatus)
+	requireMetric(t, 0.0, exporter.metrics.networkHostsUp)
+	requireMetric(t, 1.0, exporter.metrics.networkHostsDegraded)
+	requireMetric(t, 0.0, exporter.metrics.networkHostStatus.WithLabelValues("bridge"))
+	requireMetric(t, 1.0, exporter.metrics.networkHostState.WithLabelValues("bridge", "degraded"))
+	requireMetric(t, 1.0, exporter.metrics.hostDegraded.WithLabelValues("bridge", "txt_value_mismatch:sf"))
+	requireHistogram(t, exporter, "bridge", 1, 0.02)
+
+	err = publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "bridge", State: ports.HostUp, RTT: 20 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 0.0, exporter.metrics.networkHostsDegraded)
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.hostDegraded))
+}
+
+func TestMDNSStatePublisher_PublishMismatchState(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{
+			Host:     "printer",
+			State:    ports.HostMismatch,
+			RTT:      20 * time.Millisecond,
+			Reason:   "multiple_responders",
+			Conflict: true,
+		},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 1.0, exporter.metrics.hostConflict.WithLabelValues("printer"))
+
+	requireMetric(t, 1.0, exporter.metrics.networkStatus)
+	requireMetric(t, 0.0, exporter.metrics.networkHostsUp)
+	requireMetric(t, 1.0, exporter.metrics.networkHostsMismatch)
+	requireMetric(t, 0.0, exporter.metrics.networkHostStatus.WithLabelValues("printer"))
+	requireMetric(t, 1.0, exporter.metrics.networkHostState.WithLabelValues("printer", "mismatch"))
+	requireMetric(t, 1.0, exporter.metrics.hostMismatch.WithLabelValues("printer", "multiple_responders"))
+	requireHistogram(t, exporter, "printer", 1, 0.02)
+
+	err = publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer", State: ports.HostUp, RTT: 20 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 0.0, exporter.metrics.networkHostsMismatch)
+	requireMetric(t, 0.0, exporter.metrics.hostConflict.WithLabelValues("printer"))
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.hostMismatch))
+}
+
+func TestMDNSStatePublisher_PublishAddressChanges(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	var (
+		v4Old = netip.MustParseAddr("192.168.1.10")
+		v4New = netip.MustParseAddr("192.168.1.20")
+		v6    = netip.MustParseAddr("fe80::1")
+	)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer", State: ports.HostUp, KnownAddrs: []netip.Addr{v4Old, v6}},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 0.0, exporter.metrics.hostAddrChanges.WithLabelValues("printer"))
+	requireMetric(t, 1.0, exporter.metrics.hostAddrInfo.WithLabelValues("printer", "ipv4", "192.168.1.10"))
+	requireMetric(t, 1.0, exporter.metrics.hostAddrInfo.WithLabelValues("printer", "ipv6", "fe80::1"))
+
+	err = publisher.Publish(ctx, []ports.ProbeResult{
+		{
+			Host:       "printer",
+			State:      ports.HostUp,
+			KnownAddrs: []netip.Addr{v4New, v6},
+			AddrChange: &ports.AddrChange{Previous: []netip.Addr{v4Old, v6}, Current: []netip.Addr{v4New, v6}},
+		},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 1.0, exporter.metrics.hostAddrChanges.WithLabelValues("printer"))
+	require.Equal(t, 2, testutil.CollectAndCount(exporter.metrics.hostAddrInfo))
+	requireMetric(t, 1.0, exporter.metrics.hostAddrInfo.WithLabelValues("printer", "ipv4", "192.168.1.20"))
+
+	err = publisher.Publish(ctx, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.hostAddrChanges))
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.hostAddrInfo))
+}
+
+func TestMDNSStatePublisher_PublishFlappingState(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer", State: ports.HostDown, Flapped: true},
+		{Host: "switch", State: ports.HostUp},
+	})
+	require.NoError(t, err)
+
+	err = publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer", State: ports.HostFlapping, Flapped: true},
+		{Host: "switch", State: ports.HostUp},
+	})
+	require.NoError(t, err)
+
+	requireMetric(t, 2.0, exporter.metrics.hostFlaps.WithLabelValues("printer"))
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.metrics.hostFlaps))
+
+	requireMetric(t, 1.0, exporter.metrics.networkHostsFlapping)
+	requireMetric(t, 0.0, exporter.metrics.networkHostStatus.WithLabelValues("printer"))
+	requireMetric(t, 1.0, exporter.metrics.networkHostState.WithLabelValues("printer", "flapping"))
+
+	err = publisher.Publish(ctx, []ports.ProbeResult{{Host: "switch", State: ports.HostUp}})
+	require.NoError(t, err)
+
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.hostFlaps))
+}
+
+func TestMDNSStatePublisher_PublishAttempts(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "printer", State: ports.HostUp, RTT: 10 * time.Millisecond, Attempts: 2},
+		{Host: "switch", State: ports.HostDown, Attempts: 3},
+		{Host: "passive", State: ports.HostUp},
+	})
+	require.NoError(t, err)
+
+	requireHistogramOf(t, exporter, prefix+"probe_attempts", "printer", 1, 2)
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.metrics.probeAttempts))
+}
+
+func TestMDNSStatePublisher_SkipsReusedResultsInHistograms(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	result := ports.ProbeResult{Host: "printer", State: ports.HostUp, RTT: 10 * time.Millisecond, Attempts: 2}
+
+	require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{result}))
+
+	// The host is not due yet, so its result is published again on the following cycles.
+	result.Reused = true
+
+	for range 3 {
+		require.NoError(t, publisher.Publish(ctx, []ports.ProbeResult{result}))
+	}
+
+	requireHistogram(t, exporter, "printer", 1, 0.01)
+	requireHistogramOf(t, exporter, prefix+"probe_attempts", "printer", 1, 2)
+	requireMetric(t, 1.0, exporter.metrics.networkHostStatus.WithLabelValues("printer"))
+}
+
+func TestMDNSStatePublisher_RemovesStaleHosts(t *testing.T) {
+	ctx := context.Background()
+	exporter, publisher := newTestPublisher(t)
+
+	err := publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "host-kept", State: ports.HostUp, RTT: 10 * time.Millisecond},
+		{Host: "host-removed", State: ports.HostUp, RTT: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	err = publisher.Publish(ctx, []ports.ProbeResult{
+		{Host: "host-kept", State: ports.HostUp, RTT: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.metrics.networkHostStatus))
+	require.Equal(t, 6, testutil.CollectAndCount(exporter.metrics.networkHostState))
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.metrics.probeDuration))
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.metrics.hostConflict))
+	requireMetric(t, 1.0, exporter.metrics.networkHostsTotal)
+
+	err = publisher.Publish(ctx, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.networkHostStatus))
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.networkHostState))
+	require.Equal(t, 0, testutil.CollectAndCount(exporter.metrics.probeDuration))
+}
+
 func newTestPublisher(t *testing.T) (*Exporter, *MDNSStatePublisher) {
 	t.Helper()
 
-	exporter, err := NewExporter()
+	exporter, err := NewExporter(ExporterOptions{})
 	require.NoError(t, err)
 
 	publisher := NewMDNSStatePublisher(slog.New(slog.NewTextHandler(io.Discard, nil)), exporter)
@@ -65,6 +294,38 @@ func newTestPublisher(t *testing.T) (*Exporter, *MDNSStatePublisher) {
 	return exporter, publisher
 }
 
+func requireHistogram(t *testing.T, exporter *Exporter, host string, count uint64, sum float64) {
+	t.Helper()
+
+	requireHistogramOf(t, exporter, prefix+"probe_duration_seconds", host, count, sum)
+}
+
+func requireHistogramOf(t *testing.T, exporter *Exporter, name, host string, count uint64, sum float64) {
+	t.Helper()
+
+	families, err := exporter.reg.Gather()
+	require.NoError(t, err)
+
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			if m.GetLabel()[0].GetValue() != host {
+				continue
+			}
+
+			require.Equal(t, count, m.GetHistogram().GetSampleCount())
+			require.InDelta(t, sum, m.GetHistogram().GetSampleSum(), 0.001)
+
+			return
+		}
+	}
+
+	require.Failf(t, "histogram not found", "no %s histogram for host %s", name, host)
+}
+
 func requireMetric(t *testing.T, expected float64, metric prometheus.Collector) {
 	t.Helper()
 
@@ -1,22 +1,50 @@
 package prometheus
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type metrics struct {
-	networkStatus     prometheus.Gauge
-	networkHostsTotal prometheus.Gauge
-	networkHostsUp    prometheus.Gauge
-	networkHostsDown  prometheus.Gauge
-	networkHostStatus *prometheus.GaugeVec
+	networkStatus        prometheus.Gauge
+	networkHostsTotal    prometheus.Gauge
+	networkHostsUp       prometheus.Gauge
+	networkHostsDown     prometheus.Gauge
+	networkHostsError    prometheus.Gauge
+	networkHostsDegraded prometheus.Gauge
+	networkHostsMismatch prometheus.Gauge
+	networkHostsFlapping prometheus.Gauge
+	networkHostStatus    *prometheus.GaugeVec
+	networkHostState     *prometheus.GaugeVec
+	hostDegraded         *prometheus.GaugeVec
+	hostMismatch         *prometheus.GaugeVec
+	hostConflict         *prometheus.GaugeVec
+	hostAddrChanges      *prometheus.CounterVec
+	hostFlaps            *prometheus.CounterVec
+	hostAddrInfo         *prometheus.GaugeVec
+	probeDuration        *prometheus.HistogramVec
+	probeAttempts        *prometheus.HistogramVec
+	hostInfo             *hostInfoCollector
 }
 
 const (
 	prefix = "mdns_"
 )
 
-func newMetrics(reg *prometheus.Registry) (*metrics, error) {
+func newMetrics(reg *prometheus.Registry, opts ExporterOptions) (*metrics, error) {
+	probeDurationOpts := prometheus.HistogramOpts{
+		Name:    prefix + "probe_duration_seconds",
+		Help:    "Round-trip time of successful mDNS probes of a specific host",
+		Buckets: prometheus.DefBuckets,
+	}
+
+	if opts.NativeHistograms {
+		probeDurationOpts.NativeHistogramBucketFactor = 1.1
+		probeDurationOpts.NativeHistogramMaxBucketNumber = 100
+		probeDurationOpts.NativeHistogramMinResetDuration = time.Hour
+	}
+
 	m := &metrics{
 		networkStatus: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "network_status",
@@ -34,10 +62,61 @@ func newMetrics(reg *prometheus.Registry) (*metrics, error) {
 			Name: prefix + "network_hosts_down",
 			Help: "Number of hosts down on the network",
 		}),
+		networkHostsError: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "network_hosts_error",
+			Help: "Number of hosts which could not be probed",
+		}),
+		networkHostsDegraded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "network_hosts_degraded",
+			Help: "Number of hosts answering with records that fail their expectations",
+		}),
+		networkHostsMismatch: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "network_hosts_mismatch",
+			Help: "Number of hosts answering from unexpected addresses or from several responders",
+		}),
+		networkHostsFlapping: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "network_hosts_flapping",
+			Help: "Number of hosts changing between up and not up too often",
+		}),
 		networkHostStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: prefix + "network_host_status",
-			Help: "Status of a specific host (1: up, 0: down)",
+			Help: "Status of a specific host (1: up, 0: down, degraded, mismatch, flapping or error)",
+		}, []string{"host"}),
+		networkHostState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prefix + "network_host_state",
+			Help: "State of a specific host, 1 for the current state and 0 for the others",
+		}, []string{"host", "state"}),
+		hostDegraded: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prefix + "host_degraded",
+			Help: "Set to 1 with the reason while a specific host is degraded",
+		}, []string{"host", "reason"}),
+		hostMismatch: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prefix + "host_mismatch",
+			Help: "Set to 1 with the reason while a specific host is mismatched",
+		}, []string{"host", "reason"}),
+		hostConflict: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prefix + "host_conflict",
+			Help: "Set to 1 while several responders answer for the name of a specific host",
+		}, []string{"host"}),
+		hostAddrChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "host_address_changes_total",
+			Help: "Number of times the addresses of a specific host changed",
+		}, []string{"host"}),
+		hostFlaps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "host_flaps_total",
+			Help: "Number of times the probes of a specific host changed between up and not up",
+		}, []string{"host"}),
+		hostAddrInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prefix + "host_address_info",
+			Help: "Set to 1 for every last-known address of a specific host",
+		}, []string{"host", "family", "address"}),
+		probeDuration: prometheus.NewHistogramVec(probeDurationOpts, []string{"host"}),
+		probeAttempts: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    prefix + "probe_attempts",
+			Help:    "Number of mDNS queries sent until a specific host answered",
+			Buckets: prometheus.LinearBuckets(1, 1, 5),
 		}, []string{"host"}),
+		hostInfo: newHostInfoCollector(),
 	}
 
 	err := register(reg,
@@ -45,7 +124,21 @@ func newMetrics(reg *prometheus.Registry) (*metrics, error) {
 		m.networkHostsTotal,
 		m.networkHostsUp,
 		m.networkHostsDown,
+		m.networkHostsError,
+		m.networkHostsDegraded,
+		m.networkHostsMismatch,
+		m.networkHostsFlapping,
 		m.networkHostStatus,
+		m.networkHostState,
+		m.hostDegraded,
+		m.hostMismatch,
+		m.hostConflict,
+		m.hostAddrChanges,
+		m.hostFlaps,
+		m.hostAddrInfo,
+		m.probeDuration,
+		m.probeAttempts,
+		m.hostInfo,
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,169 @@
+package prometheus
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var _ http.Handler = (*ProbeHandler)(nil)
+
+// scrapeTimeoutOffset leaves Prometheus some room to receive the response before its scrape timeout elapses.
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// ProbeHandler probes a single target on demand and responds with a fresh registry of its results,
+// in the manner of the blackbox exporter's /probe endpoint.
+type ProbeHandler struct {
+	logger *slog.Logger
+	probe  ports.MDNSProbe
+	// policy is how targets are queried, its timeout is the default one of the timeout parameter.
+	policy ports.QueryPolicy
+}
+
+func NewProbeHandler(logger *slog.Logger, probe ports.MDNSProbe, policy ports.QueryPolicy) *ProbeHandler {
+	return &ProbeHandler{
+		logger: logger,
+		probe:  probe,
+		policy: policy,
+	}
+}
+
+func (h *ProbeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := r.URL.Query()
+
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	policy := h.policy
+
+	timeout, err := h.probeTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policy.Timeout = timeout
+
+	reg := prometheus.NewRegistry()
+	m := newProbeMetrics(reg)
+
+	start := time.Now()
+	result, err := h.probe.Probe(ctx, target, policy)
+	m.duration.Set(time.Since(start).Seconds())
+
+	logger := h.logger.With(slog.String("target", target))
+
+	switch {
+	case err != nil:
+		logger.WarnContext(ctx, "Failed to probe target", logging.Error(err))
+	case result.State == ports.HostUp:
+		m.success.Set(1)
+		m.rtt.Set(result.RTT.Seconds())
+		m.ipProtocol.Set(ipProtocol(result.Family))
+		m.attempts.Set(float64(result.Attempts))
+
+		for _, addr := range result.Addrs {
+			m.addrInfo.WithLabelValues(addr.String()).Set(1)
+		}
+	default:
+		logger.DebugContext(ctx, "Target is not up", slog.String("state", result.State.String()))
+	}
+
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// probeTimeout returns the timeout requested by the timeout parameter, or the default one,
+// shortened to fit into the scrape timeout announced by Prometheus.
+func (h *ProbeHandler) probeTimeout(r *http.Request) (time.Duration, error) {
+	timeout := h.policy.Timeout
+
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid timeout parameter %q", v)
+		}
+
+		timeout = d
+	}
+
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		secs, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid X-Prometheus-Scrape-Timeout-Seconds header %q", v)
+		}
+
+		scrapeTimeout := time.Duration(secs*float64(time.Second)) - scrapeTimeoutOffset
+		if scrapeTimeout > 0 && scrapeTimeout < timeout {
+			timeout = scrapeTimeout
+		}
+	}
+
+	return timeout, nil
+}
+
+type probeMetrics struct {
+	success    prometheus.Gauge
+	duration   prometheus.Gauge
+	rtt        prometheus.Gauge
+	ipProtocol prometheus.Gauge
+	attempts   prometheus.Gauge
+	addrInfo   *prometheus.GaugeVec
+}
+
+func newProbeMetrics(reg *prometheus.Registry) *probeMetrics {
+	m := &probeMetrics{
+		success: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Whether the target responded to the mDNS probe (1: up, 0: down or error)",
+		}),
+		duration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "Duration of the probe in seconds",
+		}),
+		rtt: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_mdns_rtt_seconds",
+			Help: "Round-trip time of the mDNS query in seconds",
+		}),
+		ipProtocol: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_ip_protocol",
+			Help: "IP protocol of the answer (4 or 6)",
+		}),
+		attempts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_mdns_attempts",
+			Help: "Number of mDNS queries sent until the target answered",
+		}),
+		addrInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "probe_mdns_address_info",
+			Help: "Address the target resolved to, always 1",
+		}, []string{"address"}),
+	}
+
+	reg.MustRegister(m.success, m.duration, m.rtt, m.ipProtocol, m.attempts, m.addrInfo)
+
+	return m
+}
+
+func ipProtocol(f ports.AddrFamily) float64 {
+	switch f {
+	case ports.FamilyIPv4:
+		return 4
+	case ports.FamilyIPv6:
+		return 6
+	case ports.FamilyUnknown:
+		return 0
+	default:
+		return 0
+	}
+}
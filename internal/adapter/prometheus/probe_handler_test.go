@@ -0,0 +1,95 @@
+package prometheus
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	portsm "github.com/khmm12/mdns-health-checker/internal/ports/mocks"
+)
+
+func TestProbeHandler_ReportsUpTarget(t *testing.T) {
+	probe := portsm.NewMockMDNSProbe(t)
+	probe.On("Probe", mock.Anything, "printer.local", testProbePolicy(5*time.Second)).Return(ports.ProbeResult{
+		State:    ports.HostUp,
+		RTT:      20 * time.Millisecond,
+		Addrs:    []netip.Addr{netip.MustParseAddr("192.168.1.10")},
+		Family:   ports.FamilyIPv4,
+		Attempts: 2,
+	}, nil)
+
+	body := serveProbe(t, probe, "/probe?target=printer.local&timeout=5s", http.StatusOK)
+
+	require.Contains(t, body, "probe_success 1\n")
+	require.Contains(t, body, "probe_mdns_rtt_seconds 0.02\n")
+	require.Contains(t, body, "probe_ip_protocol 4\n")
+	require.Contains(t, body, "probe_mdns_attempts 2\n")
+	require.Contains(t, body, `probe_mdns_address_info{address="192.168.1.10"} 1`)
+}
+
+func TestProbeHandler_ReportsFailedTarget(t *testing.T) {
+	probe := portsm.NewMockMDNSProbe(t)
+	probe.On("Probe", mock.Anything, "printer.local", testProbePolicy(10*time.Second)).
+		Return(ports.ProbeResult{}, errors.New("connection is closed"))
+
+	body := serveProbe(t, probe, "/probe?target=printer.local", http.StatusOK)
+
+	require.Contains(t, body, "probe_success 0\n")
+	require.NotContains(t, body, "probe_mdns_address_info{")
+}
+
+func TestProbeHandler_FitsTimeoutIntoScrapeTimeout(t *testing.T) {
+	probe := portsm.NewMockMDNSProbe(t)
+	probe.On("Probe", mock.Anything, "printer.local", testProbePolicy(2500*time.Millisecond)).
+		Return(ports.ProbeResult{State: ports.HostDown}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?target=printer.local", nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "3")
+
+	rec := httptest.NewRecorder()
+	newTestProbeHandler(probe).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "probe_success 0\n")
+}
+
+func TestProbeHandler_RejectsInvalidParameters(t *testing.T) {
+	probe := portsm.NewMockMDNSProbe(t)
+
+	serveProbe(t, probe, "/probe", http.StatusBadRequest)
+	serveProbe(t, probe, "/probe?target=printer.local&timeout=soon", http.StatusBadRequest)
+}
+
+func serveProbe(t *testing.T, probe ports.MDNSProbe, url string, status int) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	newTestProbeHandler(probe).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+	require.Equal(t, status, rec.Code)
+
+	return rec.Body.String()
+}
+
+func newTestProbeHandler(probe ports.MDNSProbe) *ProbeHandler {
+	return NewProbeHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), probe, testProbePolicy(10*time.Second))
+}
+
+// testProbePolicy returns the query policy of the test handler, with the timeout the target is probed with.
+func testProbePolicy(timeout time.Duration) ports.QueryPolicy {
+	return ports.QueryPolicy{
+		Timeout:         timeout,
+		Attempts:        3,
+		AttemptInterval: time.Second,
+		AnswerWindow:    250 * time.Millisecond,
+	}
+}
@@ -0,0 +1,303 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var _ ports.MDNSStatePublisher = (*Pusher)(nil)
+
+// PushFormat is the protocol of the endpoint the metrics are pushed to.
+type PushFormat string
+
+const (
+	// PushFormatPushgateway replaces the metrics of the grouping key on a Prometheus Pushgateway.
+	PushFormatPushgateway PushFormat = "pushgateway"
+	// PushFormatRemoteWrite sends the samples to a Prometheus remote-write endpoint, e.g. Prometheus, Mimir or
+	// VictoriaMetrics.
+	PushFormatRemoteWrite PushFormat = "remote-write"
+)
+
+// maxPushBackoff caps the delay between the attempts to push to an unreachable endpoint.
+const maxPushBackoff = time.Minute
+
+type PusherOptions struct {
+	URL    string
+	Format PushFormat
+	// Job is the job label of the pushed metrics.
+	Job string
+	// Grouping are the labels, in addition to the job, identifying the checker, e.g. instance=node-1. A Pushgateway
+	// groups the metrics by them, and remote write adds them to every series.
+	Grouping map[string]string
+	// Username and Password authenticate every request with basic auth. Empty sends unauthenticated requests.
+	Username string
+	Password string
+	// Timeout bounds a single request.
+	Timeout time.Duration
+	// Backoff is the delay before the first retry, doubled after every retry up to a minute.
+	Backoff time.Duration
+	// BufferSize is the number of remote-write snapshots kept while the endpoint is unreachable, the oldest ones are
+	// dropped first. A Pushgateway only keeps the latest metrics, so only the latest snapshot is kept for it.
+	BufferSize int
+}
+
+// snapshot is the metrics gathered after a check cycle.
+type snapshot struct {
+	families []*dto.MetricFamily
+	at       time.Time
+}
+
+// Pusher pushes the metrics of an Exporter after every check cycle, for checkers which cannot be scraped, e.g. behind
+// NAT. Publish takes a snapshot of the metrics, and Run pushes the snapshots in order, so an unreachable endpoint does
+// not hold up the check cycle.
+//
+// Pusher must be published to after the MDNSStatePublisher of the exporter, so the snapshot has the current results.
+type Pusher struct {
+	logger   *slog.Logger
+	client   *http.Client
+	gatherer prometheus.Gatherer
+	opts     PusherOptions
+
+	mu sync.Mutex
+	// pending are the snapshots waiting to be pushed, oldest first.
+	pending []*snapshot
+	wake    chan struct{}
+}
+
+func NewPusher(logger *slog.Logger, exporter *Exporter, opts PusherOptions) *Pusher {
+	return &Pusher{
+		logger:   logger,
+		client:   &http.Client{Timeout: opts.Timeout},
+		gatherer: exporter.reg,
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Publish takes a snapshot of the metrics and queues it to be pushed.
+func (p *Pusher) Publish(ctx context.Context, _ []ports.ProbeResult) error {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics to push: %w", err)
+	}
+
+	p.mu.Lock()
+
+	s := &snapshot{families: families, at: time.Now()}
+
+	switch {
+	case p.opts.Format == PushFormatPushgateway:
+		p.pending = []*snapshot{s}
+	case len(p.pending) >= p.opts.BufferSize:
+		p.logger.WarnContext(ctx, "Push buffer is full, dropping the oldest metrics",
+			slog.Time("at", p.pending[0].at),
+		)
+
+		p.pending = append(p.pending[1:], s)
+	default:
+		p.pending = append(p.pending, s)
+	}
+
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run pushes the queued snapshots until the context is done.
+func (p *Pusher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+			p.flush(ctx)
+		}
+	}
+}
+
+// flush pushes the queued snapshots oldest first, retrying with backoff while the endpoint is unreachable or fails.
+func (p *Pusher) flush(ctx context.Context) {
+	backoff := p.opts.Backoff
+
+	for {
+		s, ok := p.oldest()
+		if !ok {
+			return
+		}
+
+		err := p.push(ctx, s)
+
+		switch {
+		case err == nil:
+			backoff = p.opts.Backoff
+			p.done(s)
+
+			continue
+		case !retryable(err):
+			p.logger.ErrorContext(ctx, "Failed to push metrics", slog.Time("at", s.at), logging.Error(err))
+			p.done(s)
+
+			continue
+		}
+
+		p.logger.WarnContext(ctx, "Retrying metrics push",
+			slog.Time("at", s.at),
+			slog.Int("pending", p.pendingCount()),
+			slog.Duration("backoff", backoff),
+			logging.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxPushBackoff)
+	}
+}
+
+func (p *Pusher) oldest() (*snapshot, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.pending) == 0 {
+		return nil, false
+	}
+
+	return p.pending[0], true
+}
+
+// done removes the snapshot from the queue, unless a newer snapshot replaced it meanwhile.
+func (p *Pusher) done(s *snapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.pending) > 0 && p.pending[0] == s {
+		p.pending = p.pending[1:]
+	}
+}
+
+func (p *Pusher) pendingCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.pending)
+}
+
+func (p *Pusher) push(ctx context.Context, s *snapshot) error {
+	switch p.opts.Format {
+	case PushFormatRemoteWrite:
+		return p.remoteWrite(ctx, s)
+	default:
+		return p.pushgateway(ctx, s)
+	}
+}
+
+// pushgateway replaces the metrics of the grouping key on the Pushgateway. The Pushgateway responses are not told
+// apart, every failure is retried.
+func (p *Pusher) pushgateway(ctx context.Context, s *snapshot) error {
+	pusher := push.New(p.opts.URL, p.opts.Job).
+		Client(p.client).
+		Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return s.families, nil }))
+
+	if p.opts.Username != "" {
+		pusher = pusher.BasicAuth(p.opts.Username, p.opts.Password)
+	}
+
+	for name, value := range p.opts.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push to pushgateway: %w", err)
+	}
+
+	return nil
+}
+
+// remoteWrite sends the samples of the snapshot with the remote write 1.0 protocol.
+func (p *Pusher) remoteWrite(ctx context.Context, s *snapshot) error {
+	labels := map[string]string{"job": p.opts.Job}
+	for name, value := range p.opts.Grouping {
+		labels[name] = value
+	}
+
+	body := s2.EncodeSnappy(nil, encodeWriteRequest(s.families, labels, s.at))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("User-Agent", "mdns-health-checker")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if p.opts.Username != "" {
+		req.SetBasicAuth(p.opts.Username, p.opts.Password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to remote write: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	// The error message of the endpoint is kept short, the rest is drained so the connection can be reused.
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, msg: string(bytes.TrimSpace(msg))}
+	}
+
+	return nil
+}
+
+// statusError is a response of the endpoint rejecting the request.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("remote write responded with status %d", e.code)
+	}
+
+	return fmt.Sprintf("remote write responded with status %d: %s", e.code, e.msg)
+}
+
+// retryable reports whether the push may succeed later: the endpoint was unreachable, failed, or is rate limiting.
+// Other rejections, e.g. of out-of-order samples, would be repeated.
+func retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= http.StatusInternalServerError || statusErr.code == http.StatusTooManyRequests
+	}
+
+	return true
+}
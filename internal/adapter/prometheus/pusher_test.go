@@ -0,0 +1,275 @@
+package prometheus
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+type pushedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func TestPusher_PushesToPushgateway(t *testing.T) {
+	url, received := newTestPushReceiver(t, nil)
+
+	pusher := newTestPusher(t, PusherOptions{
+		URL:      url,
+		Format:   PushFormatPushgateway,
+		Grouping: map[string]string{"instance": "node-1"},
+		Username: "user",
+		Password: "pass",
+	})
+
+	require.NoError(t, pusher.Publish(t.Context(), nil))
+
+	go pusher.Run(t.Context())
+
+	req := <-received
+
+	require.Equal(t, http.MethodPut, req.method)
+	require.Equal(t, "/metrics/job/mdns-health-checker/instance/node-1", req.path)
+	require.Contains(t, string(req.body), prefix+"network_hosts_up")
+
+	user, pass, ok := (&http.Request{Header: req.header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pass", pass)
+}
+
+func TestPusher_RemoteWritesSamples(t *testing.T) {
+	url, received := newTestPushReceiver(t, nil)
+
+	pusher := newTestPusher(t, PusherOptions{
+		URL:      url,
+		Format:   PushFormatRemoteWrite,
+		Grouping: map[string]string{"instance": "node-1"},
+	})
+
+	before := time.Now()
+
+	require.NoError(t, pusher.Publish(t.Context(), nil))
+
+	go pusher.Run(t.Context())
+
+	req := <-received
+
+	require.Equal(t, http.MethodPost, req.method)
+	require.Equal(t, "snappy", req.header.Get("Content-Encoding"))
+	require.Equal(t, "application/x-protobuf", req.header.Get("Content-Type"))
+	require.Equal(t, "0.1.0", req.header.Get("X-Prometheus-Remote-Write-Version"))
+
+	written := decodeTestWriteRequest(t, req.body)
+
+	up, ok := written[prefix+"network_hosts_up"]
+	require.True(t, ok)
+	require.Equal(t, "mdns-health-checker", up.labels["job"])
+	require.Equal(t, "node-1", up.labels["instance"])
+	require.InDelta(t, 1.0, up.value, 0)
+	require.GreaterOrEqual(t, up.timestamp, before.UnixMilli())
+
+	bucket, ok := written[prefix+"probe_duration_seconds_bucket"]
+	require.True(t, ok)
+	require.Equal(t, "printer.local", bucket.labels["host"])
+	require.Contains(t, bucket.labels, "le")
+}
+
+func TestPusher_BuffersWhileUnreachable(t *testing.T) {
+	var calls atomic.Int32
+
+	url, received := newTestPushReceiver(t, func() int {
+		// The endpoint is unreachable until the third request.
+		if calls.Add(1) < 3 {
+			return http.StatusServiceUnavailable
+		}
+
+		return http.StatusNoContent
+	})
+
+	pusher := newTestPusher(t, PusherOptions{URL: url, Format: PushFormatRemoteWrite, BufferSize: 2})
+
+	require.NoError(t, pusher.Publish(t.Context(), nil))
+	time.Sleep(2 * time.Millisecond)
+
+	kept := time.Now()
+
+	for range 2 {
+		time.Sleep(2 * time.Millisecond)
+		require.NoError(t, pusher.Publish(t.Context(), nil))
+	}
+
+	go pusher.Run(t.Context())
+
+	var timestamps []int64
+
+	for range 4 {
+		req := <-received
+		timestamps = append(timestamps, decodeTestWriteRequest(t, req.body)[prefix+"network_hosts_up"].timestamp)
+	}
+
+	// The oldest snapshot was dropped, the buffered ones were retried until the endpoint came back, in order.
+	require.Greater(t, timestamps[0], kept.UnixMilli())
+	require.Equal(t, timestamps[0], timestamps[1])
+	require.Equal(t, timestamps[1], timestamps[2])
+	require.Less(t, timestamps[2], timestamps[3])
+	require.Eventually(t, func() bool { return pusher.pendingCount() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestPusher_DropsRejectedPushes(t *testing.T) {
+	url, received := newTestPushReceiver(t, func() int { return http.StatusBadRequest })
+
+	pusher := newTestPusher(t, PusherOptions{URL: url, Format: PushFormatRemoteWrite})
+
+	require.NoError(t, pusher.Publish(t.Context(), nil))
+
+	go pusher.Run(t.Context())
+
+	<-received
+
+	require.Eventually(t, func() bool { return pusher.pendingCount() == 0 }, time.Second, time.Millisecond)
+}
+
+// newTestPusher returns a pusher of an exporter with a published host, with short delays.
+func newTestPusher(t *testing.T, opts PusherOptions) *Pusher {
+	t.Helper()
+
+	exporter, publisher := newTestPublisher(t)
+
+	require.NoError(t, publisher.Publish(context.Background(), []ports.ProbeResult{
+		{Host: "printer.local", State: ports.HostUp, RTT: 30 * time.Millisecond},
+	}))
+
+	opts.Job = "mdns-health-checker"
+	opts.Timeout = time.Second
+	opts.Backoff = time.Millisecond
+
+	if opts.BufferSize == 0 {
+		opts.BufferSize = 10
+	}
+
+	return NewPusher(slog.New(slog.NewTextHandler(io.Discard, nil)), exporter, opts)
+}
+
+// newTestPushReceiver returns the URL of an endpoint responding with the status, or 200 if nil.
+func newTestPushReceiver(t *testing.T, status func() int) (string, <-chan pushedRequest) {
+	t.Helper()
+
+	received := make(chan pushedRequest, 10)
+
+	var mu sync.Mutex
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if status != nil {
+			w.WriteHeader(status())
+		}
+
+		received <- pushedRequest{method: r.Method, path: r.URL.Path, header: r.Header, body: body}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL, received
+}
+
+type writtenSample struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeTestWriteRequest decodes a snappy-compressed WriteRequest, returning a sample of every metric name.
+func decodeTestWriteRequest(t *testing.T, body []byte) map[string]writtenSample {
+	t.Helper()
+
+	raw, err := s2.Decode(nil, body)
+	require.NoError(t, err)
+
+	samples := make(map[string]writtenSample)
+
+	eachTestField(t, raw, func(_ protowire.Number, ts []byte) {
+		s := writtenSample{labels: make(map[string]string)}
+
+		eachTestField(t, ts, func(num protowire.Number, v []byte) {
+			switch num {
+			case timeSeriesLabels:
+				var name, value string
+
+				eachTestField(t, v, func(num protowire.Number, v []byte) {
+					if num == labelName {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+
+				s.labels[name] = value
+			case timeSeriesSamples:
+				s.value, s.timestamp = decodeTestSample(t, v)
+			}
+		})
+
+		samples[s.labels["__name__"]] = s
+	})
+
+	return samples
+}
+
+func decodeTestSample(t *testing.T, b []byte) (float64, int64) {
+	t.Helper()
+
+	num, _, n := protowire.ConsumeTag(b)
+	require.Equal(t, protowire.Number(sampleValue), num)
+
+	bits, m := protowire.ConsumeFixed64(b[n:])
+	b = b[n+m:]
+
+	num, _, n = protowire.ConsumeTag(b)
+	require.Equal(t, protowire.Number(sampleTimestamp), num)
+
+	ts, m := protowire.ConsumeVarint(b[n:])
+	require.Positive(t, m)
+
+	return math.Float64frombits(bits), int64(ts)
+}
+
+// eachTestField calls fn with every length-delimited field of the message.
+func eachTestField(t *testing.T, b []byte, fn func(num protowire.Number, v []byte)) {
+	t.Helper()
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.Positive(t, n)
+		require.Equal(t, protowire.BytesType, typ)
+
+		v, m := protowire.ConsumeBytes(b[n:])
+		require.Positive(t, m)
+
+		fn(num, v)
+
+		b = b[n+m:]
+	}
+}
@@ -0,0 +1,168 @@
+package prometheus
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the remote write 1.0 protobuf messages, see prompb/remote.proto and prompb/types.proto.
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// series is a sample of a single time series.
+type series struct {
+	labels map[string]string
+	value  float64
+}
+
+// encodeWriteRequest encodes the metric families as a remote write WriteRequest of samples taken at the time.
+// Histograms and summaries are split into their classic series, e.g. _bucket, _sum and _count; extra labels are
+// added to every series which does not have them.
+func encodeWriteRequest(families []*dto.MetricFamily, extra map[string]string, at time.Time) []byte {
+	var b []byte
+
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			for _, s := range metricSeries(mf.GetName(), mf.GetType(), m) {
+				for name, value := range extra {
+					if _, ok := s.labels[name]; !ok {
+						s.labels[name] = value
+					}
+				}
+
+				b = protowire.AppendTag(b, writeRequestTimeseries, protowire.BytesType)
+				b = protowire.AppendBytes(b, encodeTimeSeries(s, at))
+			}
+		}
+	}
+
+	return b
+}
+
+// metricSeries returns the series of the metric, each with its own labels.
+func metricSeries(name string, typ dto.MetricType, m *dto.Metric) []series {
+	labels := make(labelSet, len(m.GetLabel()))
+	for _, lp := range m.GetLabel() {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+
+	switch typ {
+	case dto.MetricType_COUNTER:
+		return []series{{labels: labels.with("__name__", name), value: m.GetCounter().GetValue()}}
+	case dto.MetricType_GAUGE:
+		return []series{{labels: labels.with("__name__", name), value: m.GetGauge().GetValue()}}
+	case dto.MetricType_SUMMARY:
+		return summarySeries(name, labels, m.GetSummary())
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		return histogramSeries(name, labels, m.GetHistogram())
+	default:
+		return []series{{labels: labels.with("__name__", name), value: m.GetUntyped().GetValue()}}
+	}
+}
+
+func summarySeries(name string, labels labelSet, s *dto.Summary) []series {
+	out := make([]series, 0, len(s.GetQuantile())+2)
+
+	for _, q := range s.GetQuantile() {
+		out = append(out, series{
+			labels: labels.with("__name__", name, "quantile", formatFloat(q.GetQuantile())),
+			value:  q.GetValue(),
+		})
+	}
+
+	return append(out,
+		series{labels: labels.with("__name__", name+"_sum"), value: s.GetSampleSum()},
+		series{labels: labels.with("__name__", name+"_count"), value: float64(s.GetSampleCount())},
+	)
+}
+
+// histogramSeries returns the classic series of the histogram, the buckets of native histograms are not sent.
+func histogramSeries(name string, labels labelSet, h *dto.Histogram) []series {
+	out := make([]series, 0, len(h.GetBucket())+3)
+
+	for _, bucket := range h.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), +1) {
+			continue
+		}
+
+		out = append(out, series{
+			labels: labels.with("__name__", name+"_bucket", "le", formatFloat(bucket.GetUpperBound())),
+			value:  float64(bucket.GetCumulativeCount()),
+		})
+	}
+
+	return append(out,
+		series{labels: labels.with("__name__", name+"_bucket", "le", "+Inf"), value: float64(h.GetSampleCount())},
+		series{labels: labels.with("__name__", name+"_sum"), value: h.GetSampleSum()},
+		series{labels: labels.with("__name__", name+"_count"), value: float64(h.GetSampleCount())},
+	)
+}
+
+// labelSet are the labels of a metric, shared by its series.
+type labelSet map[string]string
+
+// with returns a copy of the labels with the name and value pairs added.
+func (l labelSet) with(pairs ...string) map[string]string {
+	out := maps.Clone(l)
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		out[pairs[i]] = pairs[i+1]
+	}
+
+	return out
+}
+
+// encodeTimeSeries encodes a TimeSeries of a single sample, with the labels sorted by name as remote write requires.
+func encodeTimeSeries(s series, at time.Time) []byte {
+	var b []byte
+
+	for _, name := range slices.Sorted(maps.Keys(s.labels)) {
+		var label []byte
+
+		label = protowire.AppendTag(label, labelName, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, labelValue, protowire.BytesType)
+		label = protowire.AppendString(label, s.labels[name])
+
+		b = protowire.AppendTag(b, timeSeriesLabels, protowire.BytesType)
+		b = protowire.AppendBytes(b, label)
+	}
+
+	var sample []byte
+
+	sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+	sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, unixMilli(at))
+
+	b = protowire.AppendTag(b, timeSeriesSamples, protowire.BytesType)
+
+	return protowire.AppendBytes(b, sample)
+}
+
+// unixMilli returns the time in milliseconds since the epoch, as the varint of the int64 timestamp field.
+func unixMilli(t time.Time) uint64 {
+	ms := t.UnixMilli()
+	if ms < 0 {
+		return 0
+	}
+
+	return uint64(ms)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var _ ports.StateChangeNotifier = (*Notifier)(nil)
+
+// SignatureHeader carries the HMAC-SHA256 of the request body as "sha256=<hex>", if a secret is configured.
+const SignatureHeader = "X-Signature-256"
+
+// ErrQueueFull is returned by Notify when the change does not fit into the queue and is dropped.
+var ErrQueueFull = errors.New("webhook queue is full")
+
+type Options struct {
+	URL string
+	// Template is a Go text/template rendering the request body from an Event. Empty sends the Event as JSON.
+	Template string
+	// Headers are added to every request, and may override the Content-Type.
+	Headers map[string]string
+	// Secret signs every request body, see SignatureHeader. Empty sends unsigned requests.
+	Secret string
+	// Timeout bounds a single request.
+	Timeout time.Duration
+	// Retries is the number of additional requests sent for a change whose delivery failed.
+	Retries int
+	// Backoff is the delay before the first retry, doubled after every retry.
+	Backoff time.Duration
+	// QueueSize is the number of changes waiting for delivery.
+	QueueSize int
+}
+
+// Event is the JSON payload of a state change, and the data of the body template.
+type Event struct {
+	Host      string    `json:"host"`
+	Previous  string    `json:"previous"`
+	Current   string    `json:"current"`
+	ChangedAt time.Time `json:"changed_at"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Notifier posts every host state change to a webhook. Changes are queued by Notify and delivered in order by Run,
+// so a slow or unreachable receiver does not hold up the check cycle.
+type Notifier struct {
+	logger *slog.Logger
+	client *http.Client
+	opts   Options
+	tmpl   *template.Template
+	queue  chan ports.StateChange
+}
+
+func NewNotifier(logger *slog.Logger, opts Options) (*Notifier, error) {
+	n := &Notifier{
+		logger: logger,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		queue:  make(chan ports.StateChange, opts.QueueSize),
+	}
+
+	if opts.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook template: %w", err)
+		}
+
+		n.tmpl = tmpl
+	}
+
+	return n, nil
+}
+
+// Notify queues the change for delivery. A change which does not fit into the queue is dropped with ErrQueueFull.
+func (n *Notifier) Notify(_ context.Context, change ports.StateChange) error {
+	select {
+	case n.queue <- change:
+		return nil
+	default:
+		return fmt.Errorf("%w, dropped the change of %s to %s", ErrQueueFull, change.Host, change.Current)
+	}
+}
+
+// Run delivers the queued changes until the context is done.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-n.queue:
+			if err := n.deliver(ctx, change); err != nil {
+				n.logger.ErrorContext(ctx, "Failed to deliver webhook",
+					slog.String("host", change.Host),
+					slog.String("state", change.Current.String()),
+					logging.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// deliver posts the change, retrying with backoff while the receiver is unreachable or fails.
+func (n *Notifier) deliver(ctx context.Context, change ports.StateChange) error {
+	body, err := n.render(Event{
+		Host:      change.Host,
+		Previous:  change.Previous.String(),
+		Current:   change.Current.String(),
+		ChangedAt: change.ChangedAt,
+		Reason:    change.Reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := n.opts.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := n.post(ctx, body)
+		if err == nil || attempt > n.opts.Retries || !retryable(err) {
+			return err
+		}
+
+		n.logger.WarnContext(ctx, "Retrying webhook delivery",
+			slog.String("host", change.Host),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			logging.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (n *Notifier) render(event Event) ([]byte, error) {
+	if n.tmpl == nil {
+		return json.Marshal(event)
+	}
+
+	var buf bytes.Buffer
+
+	if err := n.tmpl.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range n.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if n.opts.Secret != "" {
+		req.Header.Set(SignatureHeader, sign(n.opts.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Draining the body lets the connection be reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// statusError is a response of the receiver rejecting the request.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.code)
+}
+
+// retryable reports whether the delivery may succeed later: the receiver was unreachable, failed, or is rate
+// limiting. Other rejections would be repeated.
+func retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= http.StatusInternalServerError || statusErr.code == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// toJSON encodes a template value as JSON, e.g. a quoted and escaped string.
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+var testChange = ports.StateChange{
+	Host:      "printer.local",
+	Previous:  ports.HostUp,
+	Current:   ports.HostDown,
+	ChangedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func TestNotifier_PostsSignedEvent(t *testing.T) {
+	url, received := newTestReceiver(t)
+
+	n := newTestNotifier(t, Options{URL: url, Secret: "s3cret"})
+
+	require.NoError(t, n.Notify(t.Context(), testChange))
+
+	req := <-received
+
+	var event Event
+	require.NoError(t, json.Unmarshal(req.body, &event))
+	require.Equal(t, Event{
+		Host:      "printer.local",
+		Previous:  "up",
+		Current:   "down",
+		ChangedAt: testChange.ChangedAt,
+	}, event)
+
+	require.Equal(t, "application/json", req.header.Get("Content-Type"))
+	require.Equal(t, sign("s3cret", req.body), req.header.Get(SignatureHeader))
+	require.Regexp(t, `^sha256=[0-9a-f]{64}$`, req.header.Get(SignatureHeader))
+}
+
+func TestNotifier_RendersTemplateWithHeaders(t *testing.T) {
+	url, received := newTestReceiver(t)
+
+	n := newTestNotifier(t, Options{
+		URL:      url,
+		Template: `{"message": {{ printf "%s went %s" .Host .Current | json }}}`,
+		Headers:  map[string]string{"Authorization": "Bearer token", "Content-Type": "application/vnd.test+json"},
+	})
+
+	require.NoError(t, n.Notify(t.Context(), testChange))
+
+	req := <-received
+
+	require.JSONEq(t, `{"message": "printer.local went down"}`, string(req.body))
+	require.Equal(t, "Bearer token", req.header.Get("Authorization"))
+	require.Equal(t, "application/vnd.test+json", req.header.Get("Content-Type"))
+	require.Empty(t, req.header.Get(SignatureHeader))
+}
+
+func TestNotifier_RetriesFailedDeliveries(t *testing.T) {
+	var calls atomic.Int32
+
+	done := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+			close(done)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, Options{URL: srv.URL, Retries: 2})
+
+	require.NoError(t, n.Notify(t.Context(), testChange))
+
+	<-done
+
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestNotifier_DoesNotRetryRejectedDeliveries(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, Options{URL: srv.URL, Retries: 3})
+
+	err := n.deliver(t.Context(), testChange)
+	require.EqualError(t, err, "webhook responded with status 400")
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestNotifier_DropsChangesWhenQueueIsFull(t *testing.T) {
+	n, err := NewNotifier(slog.New(slog.NewTextHandler(io.Discard, nil)), Options{QueueSize: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Notify(t.Context(), testChange))
+	require.ErrorIs(t, n.Notify(t.Context(), testChange), ErrQueueFull)
+}
+
+func TestNewNotifier_RejectsInvalidTemplate(t *testing.T) {
+	_, err := NewNotifier(slog.New(slog.NewTextHandler(io.Discard, nil)), Options{Template: "{{ .Host"})
+	require.ErrorContains(t, err, "failed to parse webhook template")
+}
+
+// newTestNotifier returns a running notifier with short delays.
+func newTestNotifier(t *testing.T, opts Options) *Notifier {
+	t.Helper()
+
+	opts.Timeout = time.Second
+	opts.Backoff = time.Millisecond
+	opts.QueueSize = 10
+
+	n, err := NewNotifier(slog.New(slog.NewTextHandler(io.Discard, nil)), opts)
+	require.NoError(t, err)
+
+	go n.Run(t.Context())
+
+	return n
+}
+
+func newTestReceiver(t *testing.T) (string, <-chan receivedRequest) {
+	t.Helper()
+
+	received := make(chan receivedRequest, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		received <- receivedRequest{header: r.Header, body: body}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL, received
+}
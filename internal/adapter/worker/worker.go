@@ -19,8 +19,9 @@ type Task interface {
 type Worker struct {
 	logger *slog.Logger
 
-	interval time.Duration
-	task     Task
+	interval   time.Duration
+	intervalCh chan time.Duration
+	task       Task
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -30,9 +31,10 @@ type Worker struct {
 
 func NewWorker(logger *slog.Logger, interval time.Duration, task Task) *Worker {
 	return &Worker{
-		logger:   logger,
-		interval: interval,
-		task:     task,
+		logger:     logger,
+		interval:   interval,
+		intervalCh: make(chan time.Duration, 1),
+		task:       task,
 	}
 }
 
@@ -54,6 +56,8 @@ func (w *Worker) Start() error {
 		select {
 		case <-w.ctx.Done():
 			return nil
+		case interval := <-w.intervalCh:
+			ticker.Reset(interval)
 		case <-ticker.C:
 			err := w.run(w.ctx)
 			if err != nil && !errors.Is(err, context.Canceled) {
@@ -63,6 +67,17 @@ func (w *Worker) Start() error {
 	}
 }
 
+// SetInterval changes the interval between task executions. It takes effect from the next tick.
+func (w *Worker) SetInterval(interval time.Duration) {
+	// Drop a pending interval which has not been applied yet, only the latest one matters.
+	select {
+	case <-w.intervalCh:
+	default:
+	}
+
+	w.intervalCh <- interval
+}
+
 func (w *Worker) Shutdown(_ context.Context) error {
 	if w.cancel != nil {
 		w.cancel()
@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"context"
+	"net/netip"
+	"time"
+)
+
+// HostStatus is the last observed status of a host, accumulated over the check cycles.
+type HostStatus struct {
+	Host  string
+	State HostState
+	// LastCheck is when the host was last probed.
+	LastCheck time.Time
+	// LastChange is when the host entered its current state.
+	LastChange time.Time
+	// LastSuccess is when the host was last seen up. Zero if it has never been up.
+	LastSuccess time.Time
+	// LastSeen is when a passively monitored host last appeared in a response. Zero for active probes.
+	LastSeen time.Time
+	RTT      time.Duration
+	Addrs    []netip.Addr
+	Family   AddrFamily
+	// Reason tells why the host is degraded, empty otherwise.
+	Reason string
+	Err    error
+}
+
+type HostStatusReader interface {
+	Hosts(ctx context.Context) []HostStatus
+	Host(ctx context.Context, name string) (HostStatus, bool)
+}
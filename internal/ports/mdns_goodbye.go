@@ -0,0 +1,13 @@
+package ports
+
+import "time"
+
+// Goodbye is a host or service instance withdrawn by its responder with a goodbye packet, i.e. records with a TTL of
+// zero (RFC 6762 §10.1). Responders send them when they shut down cleanly.
+type Goodbye struct {
+	// Name is the host name, or the fully qualified instance name if Service is set.
+	Name    string
+	Service bool
+	// ReceivedAt is when the goodbye packet was received.
+	ReceivedAt time.Time
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"net/netip"
 	"time"
 )
 
@@ -11,8 +12,189 @@ const (
 	HostUnknown HostState = iota
 	HostUp
 	HostDown
+	HostError
+	// HostDegraded means the host answered but advertises records that fail its expectations.
+	HostDegraded
+	// HostMismatch means the host answered, but from unexpected addresses or from more than one responder.
+	HostMismatch
+	// HostFlapping means the host changed between up and not up too often within its flap window.
+	HostFlapping
 )
 
+func (s HostState) String() string {
+	switch s {
+	case HostUp:
+		return "up"
+	case HostDown:
+		return "down"
+	case HostError:
+		return "error"
+	case HostDegraded:
+		return "degraded"
+	case HostMismatch:
+		return "mismatch"
+	case HostFlapping:
+		return "flapping"
+	case HostUnknown:
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
+// Answered reports whether a host in the state answered its probe, even if the answer failed its expectations.
+func (s HostState) Answered() bool {
+	return s == HostUp || s == HostDegraded || s == HostMismatch
+}
+
+type AddrFamily int
+
+const (
+	FamilyUnknown AddrFamily = iota
+	FamilyIPv4
+	FamilyIPv6
+)
+
+func (f AddrFamily) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "ipv4"
+	case FamilyIPv6:
+		return "ipv6"
+	case FamilyUnknown:
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
+type ErrorClass int
+
+const (
+	ErrorClassNone ErrorClass = iota
+	// ErrorClassCanceled means the probe was interrupted by its parent context.
+	ErrorClassCanceled
+	// ErrorClassClosed means the mDNS connection was closed while probing.
+	ErrorClassClosed
+	// ErrorClassInvalidState means the probe completed but reported a state it should not.
+	ErrorClassInvalidState
+	ErrorClassOther
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassNone:
+		return "none"
+	case ErrorClassCanceled:
+		return "canceled"
+	case ErrorClassClosed:
+		return "closed"
+	case ErrorClassInvalidState:
+		return "invalid_state"
+	case ErrorClassOther:
+		return "other"
+	default:
+		return "other"
+	}
+}
+
+// ProbeResult is the outcome of a single host probe.
+//
+// The probe adapter fills State, RTT, Addrs, Family, Sources, Attempts, Service and LastSeen. Host, CheckedAt,
+// Conflict, KnownAddrs, AddrChange, Flapped, Reused, Reason, ErrorClass and Err are filled by the use case, so publishers
+// always receive one result per probed host, including the ones that failed.
+type ProbeResult struct {
+	Host  string
+	State HostState
+	// CheckedAt is the start of the check cycle the host was probed in.
+	CheckedAt time.Time
+	// RTT is the time between sending the query and receiving the first answer. Zero unless the host answered.
+	RTT time.Duration
+	// Addrs are the addresses the host resolved to.
+	Addrs []netip.Addr
+	// Family is the address family of the answer.
+	Family AddrFamily
+	// Sources are the addresses of the responders which answered for the host, if the probe observes them.
+	Sources []netip.Addr
+	// Attempts is the number of queries sent until the host answered, or in vain if it did not. Zero if the probe
+	// sends no queries.
+	Attempts int
+	// Conflict is set when several responders of the same address family answered for the host, e.g. devices
+	// cloned from the same image.
+	Conflict bool
+	// KnownAddrs are the last-known addresses of the host, per family the ones of its latest answer of that family.
+	// They are kept while the host does not answer.
+	KnownAddrs []netip.Addr
+	// AddrChange is set when the answer changed the known addresses of a family the host answered with before.
+	AddrChange *AddrChange
+	// Flapped is set when the probe found the host up and the previous one did not, or the other way around.
+	Flapped bool
+	// Reused is set when the result repeats an earlier probe, e.g. of a host which is not due yet, so its RTT and
+	// Attempts are no new measurements.
+	Reused bool
+	// Service is the resolved instance of a service check, with the records found even if the service is down.
+	// Nil for host checks.
+	Service *ServiceInstance
+	// LastSeen is when a passively monitored host last appeared in a response. Zero for active probes.
+	LastSeen time.Time
+	// Reason is a short machine-readable code telling why the host is degraded or mismatched,
+	// e.g. "txt_value_mismatch:sf".
+	Reason     string
+	ErrorClass ErrorClass
+	Err        error
+}
+
+// AddrChange describes the known addresses of a host before and after a probe.
+type AddrChange struct {
+	Previous []netip.Addr
+	Current  []netip.Addr
+}
+
+// ProbeError is returned by MDNSProbe implementations to classify why a probe failed.
+type ProbeError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *ProbeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// QueryPolicy is how a probe queries a host or service instance. A query which is not answered is sent again after
+// AttemptInterval, the interval doubling after every query as the intervals of continuous querying (RFC 6762 §5.2),
+// until Attempts queries were sent. Every query is sent within Timeout, which bounds the whole probe.
+type QueryPolicy struct {
+	Timeout time.Duration
+	// Attempts is the maximum number of queries sent. Zero or one sends a single query.
+	Attempts        int
+	AttemptInterval time.Duration
+	// AnswerWindow is how long a host probe keeps collecting answers from other responders after the first one,
+	// to detect name conflicts. It should cover the 20-120ms delay responders add to their answers (RFC 6762 §6).
+	AnswerWindow time.Duration
+}
+
+// AttemptsFit reports whether the last query of the policy is sent before its timeout elapses.
+func (p QueryPolicy) AttemptsFit() bool {
+	var at time.Duration
+
+	interval := p.AttemptInterval
+
+	for range p.Attempts - 1 {
+		at += interval
+		if at >= p.Timeout {
+			return false
+		}
+
+		interval *= 2
+	}
+
+	return true
+}
+
 type MDNSProbe interface {
-	Probe(ctx context.Context, host string, timeout time.Duration) (HostState, error)
+	Probe(ctx context.Context, host string, policy QueryPolicy) (ProbeResult, error)
 }
@@ -0,0 +1,17 @@
+package ports
+
+import "context"
+
+// ServiceCheck describes the DNS-SD service instance a service probe looks for.
+type ServiceCheck struct {
+	// Instance is the fully qualified instance name, e.g. "Office Printer._ipp._tcp.local".
+	Instance string
+	// RequireTXT makes the instance be reported up only if its TXT record is advertised too.
+	RequireTXT bool
+}
+
+// MDNSServiceProbe checks that a service instance is advertised: it is up when the service type's PTR record points
+// to the instance and the instance has an SRV record (and a TXT record if required).
+type MDNSServiceProbe interface {
+	ProbeService(ctx context.Context, check ServiceCheck, policy QueryPolicy) (ProbeResult, error)
+}
@@ -3,5 +3,5 @@ package ports
 import "context"
 
 type MDNSStatePublisher interface {
-	Publish(ctx context.Context, up, down []string) error
+	Publish(ctx context.Context, results []ProbeResult) error
 }
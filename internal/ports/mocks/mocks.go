@@ -12,6 +12,152 @@ import (
 	mock "github.com/stretchr/testify/mock"
 )
 
+// NewMockHostStatusReader creates a new instance of MockHostStatusReader. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockHostStatusReader(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockHostStatusReader {
+	mock := &MockHostStatusReader{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockHostStatusReader is an autogenerated mock type for the HostStatusReader type
+type MockHostStatusReader struct {
+	mock.Mock
+}
+
+type MockHostStatusReader_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockHostStatusReader) EXPECT() *MockHostStatusReader_Expecter {
+	return &MockHostStatusReader_Expecter{mock: &_m.Mock}
+}
+
+// Host provides a mock function for the type MockHostStatusReader
+func (_mock *MockHostStatusReader) Host(ctx context.Context, name string) (ports.HostStatus, bool) {
+	ret := _mock.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Host")
+	}
+
+	var r0 ports.HostStatus
+	var r1 bool
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (ports.HostStatus, bool)); ok {
+		return returnFunc(ctx, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ports.HostStatus); ok {
+		r0 = returnFunc(ctx, name)
+	} else {
+		r0 = ret.Get(0).(ports.HostStatus)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = returnFunc(ctx, name)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+	return r0, r1
+}
+
+// MockHostStatusReader_Host_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Host'
+type MockHostStatusReader_Host_Call struct {
+	*mock.Call
+}
+
+// Host is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *MockHostStatusReader_Expecter) Host(ctx any, name any) *MockHostStatusReader_Host_Call {
+	return &MockHostStatusReader_Host_Call{Call: _e.mock.On("Host", ctx, name)}
+}
+
+func (_c *MockHostStatusReader_Host_Call) Run(run func(ctx context.Context, name string)) *MockHostStatusReader_Host_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockHostStatusReader_Host_Call) Return(hostStatus ports.HostStatus, b bool) *MockHostStatusReader_Host_Call {
+	_c.Call.Return(hostStatus, b)
+	return _c
+}
+
+func (_c *MockHostStatusReader_Host_Call) RunAndReturn(run func(ctx context.Context, name string) (ports.HostStatus, bool)) *MockHostStatusReader_Host_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Hosts provides a mock function for the type MockHostStatusReader
+func (_mock *MockHostStatusReader) Hosts(ctx context.Context) []ports.HostStatus {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Hosts")
+	}
+
+	var r0 []ports.HostStatus
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []ports.HostStatus); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ports.HostStatus)
+		}
+	}
+	return r0
+}
+
+// MockHostStatusReader_Hosts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Hosts'
+type MockHostStatusReader_Hosts_Call struct {
+	*mock.Call
+}
+
+// Hosts is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockHostStatusReader_Expecter) Hosts(ctx any) *MockHostStatusReader_Hosts_Call {
+	return &MockHostStatusReader_Hosts_Call{Call: _e.mock.On("Hosts", ctx)}
+}
+
+func (_c *MockHostStatusReader_Hosts_Call) Run(run func(ctx context.Context)) *MockHostStatusReader_Hosts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockHostStatusReader_Hosts_Call) Return(hostStatuss []ports.HostStatus) *MockHostStatusReader_Hosts_Call {
+	_c.Call.Return(hostStatuss)
+	return _c
+}
+
+func (_c *MockHostStatusReader_Hosts_Call) RunAndReturn(run func(ctx context.Context) []ports.HostStatus) *MockHostStatusReader_Hosts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockMDNSProbe creates a new instance of MockMDNSProbe. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockMDNSProbe(t interface {
@@ -40,25 +186,25 @@ func (_m *MockMDNSProbe) EXPECT() *MockMDNSProbe_Expecter {
 }
 
 // Probe provides a mock function for the type MockMDNSProbe
-func (_mock *MockMDNSProbe) Probe(ctx context.Context, host string, timeout time.Duration) (ports.HostState, error) {
-	ret := _mock.Called(ctx, host, timeout)
+func (_mock *MockMDNSProbe) Probe(ctx context.Context, host string, policy ports.QueryPolicy) (ports.ProbeResult, error) {
+	ret := _mock.Called(ctx, host, policy)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Probe")
 	}
 
-	var r0 ports.HostState
+	var r0 ports.ProbeResult
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Duration) (ports.HostState, error)); ok {
-		return returnFunc(ctx, host, timeout)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, ports.QueryPolicy) (ports.ProbeResult, error)); ok {
+		return returnFunc(ctx, host, policy)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Duration) ports.HostState); ok {
-		r0 = returnFunc(ctx, host, timeout)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, ports.QueryPolicy) ports.ProbeResult); ok {
+		r0 = returnFunc(ctx, host, policy)
 	} else {
-		r0 = ret.Get(0).(ports.HostState)
+		r0 = ret.Get(0).(ports.ProbeResult)
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string, time.Duration) error); ok {
-		r1 = returnFunc(ctx, host, timeout)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, ports.QueryPolicy) error); ok {
+		r1 = returnFunc(ctx, host, policy)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -73,12 +219,12 @@ type MockMDNSProbe_Probe_Call struct {
 // Probe is a helper method to define mock.On call
 //   - ctx context.Context
 //   - host string
-//   - timeout time.Duration
-func (_e *MockMDNSProbe_Expecter) Probe(ctx any, host any, timeout any) *MockMDNSProbe_Probe_Call {
-	return &MockMDNSProbe_Probe_Call{Call: _e.mock.On("Probe", ctx, host, timeout)}
+//   - policy ports.QueryPolicy
+func (_e *MockMDNSProbe_Expecter) Probe(ctx any, host any, policy any) *MockMDNSProbe_Probe_Call {
+	return &MockMDNSProbe_Probe_Call{Call: _e.mock.On("Probe", ctx, host, policy)}
 }
 
-func (_c *MockMDNSProbe_Probe_Call) Run(run func(ctx context.Context, host string, timeout time.Duration)) *MockMDNSProbe_Probe_Call {
+func (_c *MockMDNSProbe_Probe_Call) Run(run func(ctx context.Context, host string, policy ports.QueryPolicy)) *MockMDNSProbe_Probe_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -88,9 +234,9 @@ func (_c *MockMDNSProbe_Probe_Call) Run(run func(ctx context.Context, host strin
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 time.Duration
+		var arg2 ports.QueryPolicy
 		if args[2] != nil {
-			arg2 = args[2].(time.Duration)
+			arg2 = args[2].(ports.QueryPolicy)
 		}
 		run(
 			arg0,
@@ -101,12 +247,111 @@ func (_c *MockMDNSProbe_Probe_Call) Run(run func(ctx context.Context, host strin
 	return _c
 }
 
-func (_c *MockMDNSProbe_Probe_Call) Return(hostState ports.HostState, err error) *MockMDNSProbe_Probe_Call {
-	_c.Call.Return(hostState, err)
+func (_c *MockMDNSProbe_Probe_Call) Return(probeResult ports.ProbeResult, err error) *MockMDNSProbe_Probe_Call {
+	_c.Call.Return(probeResult, err)
 	return _c
 }
 
-func (_c *MockMDNSProbe_Probe_Call) RunAndReturn(run func(ctx context.Context, host string, timeout time.Duration) (ports.HostState, error)) *MockMDNSProbe_Probe_Call {
+func (_c *MockMDNSProbe_Probe_Call) RunAndReturn(run func(ctx context.Context, host string, policy ports.QueryPolicy) (ports.ProbeResult, error)) *MockMDNSProbe_Probe_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockMDNSServiceProbe creates a new instance of MockMDNSServiceProbe. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockMDNSServiceProbe(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockMDNSServiceProbe {
+	mock := &MockMDNSServiceProbe{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockMDNSServiceProbe is an autogenerated mock type for the MDNSServiceProbe type
+type MockMDNSServiceProbe struct {
+	mock.Mock
+}
+
+type MockMDNSServiceProbe_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockMDNSServiceProbe) EXPECT() *MockMDNSServiceProbe_Expecter {
+	return &MockMDNSServiceProbe_Expecter{mock: &_m.Mock}
+}
+
+// ProbeService provides a mock function for the type MockMDNSServiceProbe
+func (_mock *MockMDNSServiceProbe) ProbeService(ctx context.Context, check ports.ServiceCheck, policy ports.QueryPolicy) (ports.ProbeResult, error) {
+	ret := _mock.Called(ctx, check, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ProbeService")
+	}
+
+	var r0 ports.ProbeResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, ports.ServiceCheck, ports.QueryPolicy) (ports.ProbeResult, error)); ok {
+		return returnFunc(ctx, check, policy)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, ports.ServiceCheck, ports.QueryPolicy) ports.ProbeResult); ok {
+		r0 = returnFunc(ctx, check, policy)
+	} else {
+		r0 = ret.Get(0).(ports.ProbeResult)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, ports.ServiceCheck, ports.QueryPolicy) error); ok {
+		r1 = returnFunc(ctx, check, policy)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockMDNSServiceProbe_ProbeService_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ProbeService'
+type MockMDNSServiceProbe_ProbeService_Call struct {
+	*mock.Call
+}
+
+// ProbeService is a helper method to define mock.On call
+//   - ctx context.Context
+//   - check ports.ServiceCheck
+//   - policy ports.QueryPolicy
+func (_e *MockMDNSServiceProbe_Expecter) ProbeService(ctx any, check any, policy any) *MockMDNSServiceProbe_ProbeService_Call {
+	return &MockMDNSServiceProbe_ProbeService_Call{Call: _e.mock.On("ProbeService", ctx, check, policy)}
+}
+
+func (_c *MockMDNSServiceProbe_ProbeService_Call) Run(run func(ctx context.Context, check ports.ServiceCheck, policy ports.QueryPolicy)) *MockMDNSServiceProbe_ProbeService_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 ports.ServiceCheck
+		if args[1] != nil {
+			arg1 = args[1].(ports.ServiceCheck)
+		}
+		var arg2 ports.QueryPolicy
+		if args[2] != nil {
+			arg2 = args[2].(ports.QueryPolicy)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMDNSServiceProbe_ProbeService_Call) Return(probeResult ports.ProbeResult, err error) *MockMDNSServiceProbe_ProbeService_Call {
+	_c.Call.Return(probeResult, err)
+	return _c
+}
+
+func (_c *MockMDNSServiceProbe_ProbeService_Call) RunAndReturn(run func(ctx context.Context, check ports.ServiceCheck, policy ports.QueryPolicy) (ports.ProbeResult, error)) *MockMDNSServiceProbe_ProbeService_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -139,16 +384,16 @@ func (_m *MockMDNSStatePublisher) EXPECT() *MockMDNSStatePublisher_Expecter {
 }
 
 // Publish provides a mock function for the type MockMDNSStatePublisher
-func (_mock *MockMDNSStatePublisher) Publish(ctx context.Context, up []string, down []string) error {
-	ret := _mock.Called(ctx, up, down)
+func (_mock *MockMDNSStatePublisher) Publish(ctx context.Context, results []ports.ProbeResult) error {
+	ret := _mock.Called(ctx, results)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Publish")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, []string, []string) error); ok {
-		r0 = returnFunc(ctx, up, down)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []ports.ProbeResult) error); ok {
+		r0 = returnFunc(ctx, results)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -162,25 +407,120 @@ type MockMDNSStatePublisher_Publish_Call struct {
 
 // Publish is a helper method to define mock.On call
 //   - ctx context.Context
-//   - up []string
-//   - down []string
-func (_e *MockMDNSStatePublisher_Expecter) Publish(ctx any, up any, down any) *MockMDNSStatePublisher_Publish_Call {
-	return &MockMDNSStatePublisher_Publish_Call{Call: _e.mock.On("Publish", ctx, up, down)}
+//   - results []ports.ProbeResult
+func (_e *MockMDNSStatePublisher_Expecter) Publish(ctx any, results any) *MockMDNSStatePublisher_Publish_Call {
+	return &MockMDNSStatePublisher_Publish_Call{Call: _e.mock.On("Publish", ctx, results)}
 }
 
-func (_c *MockMDNSStatePublisher_Publish_Call) Run(run func(ctx context.Context, up []string, down []string)) *MockMDNSStatePublisher_Publish_Call {
+func (_c *MockMDNSStatePublisher_Publish_Call) Run(run func(ctx context.Context, results []ports.ProbeResult)) *MockMDNSStatePublisher_Publish_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 []string
+		var arg1 []ports.ProbeResult
 		if args[1] != nil {
-			arg1 = args[1].([]string)
+			arg1 = args[1].([]ports.ProbeResult)
 		}
-		var arg2 []string
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMDNSStatePublisher_Publish_Call) Return(err error) *MockMDNSStatePublisher_Publish_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockMDNSStatePublisher_Publish_Call) RunAndReturn(run func(ctx context.Context, results []ports.ProbeResult) error) *MockMDNSStatePublisher_Publish_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockServiceBrowser creates a new instance of MockServiceBrowser. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockServiceBrowser(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockServiceBrowser {
+	mock := &MockServiceBrowser{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockServiceBrowser is an autogenerated mock type for the ServiceBrowser type
+type MockServiceBrowser struct {
+	mock.Mock
+}
+
+type MockServiceBrowser_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockServiceBrowser) EXPECT() *MockServiceBrowser_Expecter {
+	return &MockServiceBrowser_Expecter{mock: &_m.Mock}
+}
+
+// Browse provides a mock function for the type MockServiceBrowser
+func (_mock *MockServiceBrowser) Browse(ctx context.Context, service string, timeout time.Duration) ([]ports.ServiceInstance, error) {
+	ret := _mock.Called(ctx, service, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Browse")
+	}
+
+	var r0 []ports.ServiceInstance
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Duration) ([]ports.ServiceInstance, error)); ok {
+		return returnFunc(ctx, service, timeout)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Duration) []ports.ServiceInstance); ok {
+		r0 = returnFunc(ctx, service, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ports.ServiceInstance)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, time.Duration) error); ok {
+		r1 = returnFunc(ctx, service, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockServiceBrowser_Browse_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Browse'
+type MockServiceBrowser_Browse_Call struct {
+	*mock.Call
+}
+
+// Browse is a helper method to define mock.On call
+//   - ctx context.Context
+//   - service string
+//   - timeout time.Duration
+func (_e *MockServiceBrowser_Expecter) Browse(ctx any, service any, timeout any) *MockServiceBrowser_Browse_Call {
+	return &MockServiceBrowser_Browse_Call{Call: _e.mock.On("Browse", ctx, service, timeout)}
+}
+
+func (_c *MockServiceBrowser_Browse_Call) Run(run func(ctx context.Context, service string, timeout time.Duration)) *MockServiceBrowser_Browse_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Duration
 		if args[2] != nil {
-			arg2 = args[2].([]string)
+			arg2 = args[2].(time.Duration)
 		}
 		run(
 			arg0,
@@ -191,12 +531,164 @@ func (_c *MockMDNSStatePublisher_Publish_Call) Run(run func(ctx context.Context,
 	return _c
 }
 
-func (_c *MockMDNSStatePublisher_Publish_Call) Return(err error) *MockMDNSStatePublisher_Publish_Call {
+func (_c *MockServiceBrowser_Browse_Call) Return(serviceInstances []ports.ServiceInstance, err error) *MockServiceBrowser_Browse_Call {
+	_c.Call.Return(serviceInstances, err)
+	return _c
+}
+
+func (_c *MockServiceBrowser_Browse_Call) RunAndReturn(run func(ctx context.Context, service string, timeout time.Duration) ([]ports.ServiceInstance, error)) *MockServiceBrowser_Browse_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ServiceTypes provides a mock function for the type MockServiceBrowser
+func (_mock *MockServiceBrowser) ServiceTypes(ctx context.Context, timeout time.Duration) ([]string, error) {
+	ret := _mock.Called(ctx, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ServiceTypes")
+	}
+
+	var r0 []string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Duration) ([]string, error)); ok {
+		return returnFunc(ctx, timeout)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, time.Duration) []string); ok {
+		r0 = returnFunc(ctx, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, time.Duration) error); ok {
+		r1 = returnFunc(ctx, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockServiceBrowser_ServiceTypes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ServiceTypes'
+type MockServiceBrowser_ServiceTypes_Call struct {
+	*mock.Call
+}
+
+// ServiceTypes is a helper method to define mock.On call
+//   - ctx context.Context
+//   - timeout time.Duration
+func (_e *MockServiceBrowser_Expecter) ServiceTypes(ctx any, timeout any) *MockServiceBrowser_ServiceTypes_Call {
+	return &MockServiceBrowser_ServiceTypes_Call{Call: _e.mock.On("ServiceTypes", ctx, timeout)}
+}
+
+func (_c *MockServiceBrowser_ServiceTypes_Call) Run(run func(ctx context.Context, timeout time.Duration)) *MockServiceBrowser_ServiceTypes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 time.Duration
+		if args[1] != nil {
+			arg1 = args[1].(time.Duration)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockServiceBrowser_ServiceTypes_Call) Return(strings []string, err error) *MockServiceBrowser_ServiceTypes_Call {
+	_c.Call.Return(strings, err)
+	return _c
+}
+
+func (_c *MockServiceBrowser_ServiceTypes_Call) RunAndReturn(run func(ctx context.Context, timeout time.Duration) ([]string, error)) *MockServiceBrowser_ServiceTypes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockStateChangeNotifier creates a new instance of MockStateChangeNotifier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockStateChangeNotifier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockStateChangeNotifier {
+	mock := &MockStateChangeNotifier{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockStateChangeNotifier is an autogenerated mock type for the StateChangeNotifier type
+type MockStateChangeNotifier struct {
+	mock.Mock
+}
+
+type MockStateChangeNotifier_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockStateChangeNotifier) EXPECT() *MockStateChangeNotifier_Expecter {
+	return &MockStateChangeNotifier_Expecter{mock: &_m.Mock}
+}
+
+// Notify provides a mock function for the type MockStateChangeNotifier
+func (_mock *MockStateChangeNotifier) Notify(ctx context.Context, change ports.StateChange) error {
+	ret := _mock.Called(ctx, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Notify")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, ports.StateChange) error); ok {
+		r0 = returnFunc(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockStateChangeNotifier_Notify_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Notify'
+type MockStateChangeNotifier_Notify_Call struct {
+	*mock.Call
+}
+
+// Notify is a helper method to define mock.On call
+//   - ctx context.Context
+//   - change ports.StateChange
+func (_e *MockStateChangeNotifier_Expecter) Notify(ctx any, change any) *MockStateChangeNotifier_Notify_Call {
+	return &MockStateChangeNotifier_Notify_Call{Call: _e.mock.On("Notify", ctx, change)}
+}
+
+func (_c *MockStateChangeNotifier_Notify_Call) Run(run func(ctx context.Context, change ports.StateChange)) *MockStateChangeNotifier_Notify_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 ports.StateChange
+		if args[1] != nil {
+			arg1 = args[1].(ports.StateChange)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockStateChangeNotifier_Notify_Call) Return(err error) *MockStateChangeNotifier_Notify_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockMDNSStatePublisher_Publish_Call) RunAndReturn(run func(ctx context.Context, up []string, down []string) error) *MockMDNSStatePublisher_Publish_Call {
+func (_c *MockStateChangeNotifier_Notify_Call) RunAndReturn(run func(ctx context.Context, change ports.StateChange) error) *MockStateChangeNotifier_Notify_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"context"
+	"net/netip"
+	"time"
+)
+
+// ServiceInstance is a DNS-SD service instance resolved from its PTR, SRV, TXT and address records.
+type ServiceInstance struct {
+	// Instance is the user-visible instance label, e.g. "Office Printer".
+	Instance string
+	// Service is the service type including the domain, e.g. "_ipp._tcp.local".
+	Service string
+	// Host is the SRV target, empty if no SRV record was received.
+	Host  string
+	Port  uint16
+	Text  []string
+	Addrs []netip.Addr
+}
+
+// Name returns the fully qualified instance name, e.g. "Office Printer._ipp._tcp.local".
+func (s ServiceInstance) Name() string {
+	return s.Instance + "." + s.Service
+}
+
+type ServiceBrowser interface {
+	// ServiceTypes returns the service types advertised on the network within the timeout.
+	ServiceTypes(ctx context.Context, timeout time.Duration) ([]string, error)
+	// Browse returns the instances of the service type that answered within the timeout.
+	Browse(ctx context.Context, service string, timeout time.Duration) ([]ServiceInstance, error)
+}
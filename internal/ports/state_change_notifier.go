@@ -0,0 +1,24 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// StateChange is a transition of a host between two reported states.
+type StateChange struct {
+	Host string
+	// Previous is the state before the change, HostUnknown for a host reported for the first time.
+	Previous HostState
+	Current  HostState
+	// ChangedAt is when the new state was observed: the check cycle of the probe, or the receipt of a goodbye.
+	ChangedAt time.Time
+	// Reason tells why the host is degraded or mismatched, empty otherwise.
+	Reason string
+}
+
+// StateChangeNotifier is notified of every state change of a host, unlike a MDNSStatePublisher which receives the
+// results of every check cycle. Notify is called from the check cycle, so it should not block on slow receivers.
+type StateChangeNotifier interface {
+	Notify(ctx context.Context, change StateChange) error
+}
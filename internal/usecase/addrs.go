@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"slices"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func (u *CheckMDNSUseCase) trackAddrs(ctx context.Context, result ports.ProbeResult) ports.ProbeResult {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	known := u.known[result.Host]
+
+	if len(result.Addrs) > 0 {
+		updated, changed := mergeAddrs(known, result.Addrs)
+		if changed {
+			result.AddrChange = &ports.AddrChange{Previous: known, Current: updated}
+
+			u.logger.InfoContext(ctx, "Host address changed",
+				slog.String("host", result.Host),
+				slog.Any("previous", known),
+				slog.Any("current", updated),
+			)
+		}
+
+		known = updated
+		u.known[result.Host] = known
+	}
+
+	result.KnownAddrs = known
+
+	return result
+}
+
+// Reasons of a mismatched host.
+const (
+	reasonUnexpectedAddress  = "unexpected_address"
+	reasonMultipleResponders = "multiple_responders"
+)
+
+// verifyAddrs reports the host mismatched if it resolved outside of its expected networks or several responders of
+// the same family answered for it. mDNS has no authentication, so any device on the link can answer for any name.
+func (u *CheckMDNSUseCase) verifyAddrs(
+	ctx context.Context,
+	host HostConfig,
+	result ports.ProbeResult,
+) ports.ProbeResult {
+	for _, is4 := range []bool{true, false} {
+		if sources := filterFamily(result.Sources, is4); len(sources) > 1 {
+			result.Conflict = true
+
+			u.logger.WarnContext(ctx, "Host name conflict",
+				slog.String("host", host.Name),
+				slog.Any("sources", sources),
+				slog.Any("addrs", result.Addrs),
+			)
+		}
+	}
+
+	if unexpected := unexpectedAddrs(result.Addrs, host.ExpectedAddrs); len(unexpected) > 0 {
+		result.State = ports.HostMismatch
+		result.Reason = reasonUnexpectedAddress
+
+		u.logger.WarnContext(ctx, "Host resolved to unexpected addresses",
+			slog.String("host", host.Name),
+			slog.Any("addrs", unexpected),
+		)
+	} else if result.Conflict {
+		result.State = ports.HostMismatch
+		result.Reason = reasonMultipleResponders
+	}
+
+	return result
+}
+
+// mergeAddrs replaces the known addresses of the families present in addrs, as a dual-stack host may answer with
+// either family. The first addresses of a family are not a change.
+func mergeAddrs(known, addrs []netip.Addr) ([]netip.Addr, bool) {
+	addrs = sortedAddrs(addrs)
+
+	var (
+		merged  []netip.Addr
+		changed bool
+	)
+
+	for _, is4 := range []bool{true, false} {
+		prev := filterFamily(known, is4)
+		next := filterFamily(addrs, is4)
+
+		if len(next) == 0 {
+			merged = append(merged, prev...)
+			continue
+		}
+
+		if len(prev) > 0 && !slices.Equal(prev, next) {
+			changed = true
+		}
+
+		merged = append(merged, next...)
+	}
+
+	return merged, changed
+}
+
+func filterFamily(addrs []netip.Addr, is4 bool) []netip.Addr {
+	var out []netip.Addr
+
+	for _, addr := range addrs {
+		if addr.Unmap().Is4() == is4 {
+			out = append(out, addr)
+		}
+	}
+
+	return out
+}
+
+func sortedAddrs(addrs []netip.Addr) []netip.Addr {
+	sorted := slices.Clone(addrs)
+	slices.SortFunc(sorted, netip.Addr.Compare)
+
+	return slices.Compact(sorted)
+}
@@ -2,81 +2,448 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
-	"golang.org/x/sync/errgroup"
-
+	"github.com/khmm12/mdns-health-checker/internal/common/logging"
 	"github.com/khmm12/mdns-health-checker/internal/ports"
 )
 
+// dueSlackDivisor lets a probe run early by a share of the host interval, absorbing the jitter of the ticker.
+const dueSlackDivisor = 10
+
 type CheckMDNSUseCase struct {
-	logger    *slog.Logger
-	publisher ports.MDNSStatePublisher
-	probe     ports.MDNSProbe
-	timeout   time.Duration
+	logger       *slog.Logger
+	publishers   []ports.MDNSStatePublisher
+	probe        ports.MDNSProbe
+	serviceProbe ports.MDNSServiceProbe
+	now          func() time.Time
+
+	mu        sync.Mutex
+	notifiers []ports.StateChangeNotifier
+	hosts     []HostConfig
+	last      map[string]ports.ProbeResult
+	known     map[string][]netip.Addr
+	history   map[string]*hostHistory
 }
 
 func NewCheckMDNSUseCase(
 	logger *slog.Logger,
 	probe ports.MDNSProbe,
-	publisher ports.MDNSStatePublisher,
-	timeout time.Duration,
+	serviceProbe ports.MDNSServiceProbe,
+	publishers ...ports.MDNSStatePublisher,
 ) *CheckMDNSUseCase {
 	return &CheckMDNSUseCase{
-		logger:    logger,
-		publisher: publisher,
-		probe:     probe,
-		timeout:   timeout,
+		logger:       logger,
+		publishers:   publishers,
+		probe:        probe,
+		serviceProbe: serviceProbe,
+		now:          time.Now,
+		last:         make(map[string]ports.ProbeResult),
+		known:        make(map[string][]netip.Addr),
+		history:      make(map[string]*hostHistory),
+	}
+}
+
+// Subscribe makes the notifier be notified of the state changes of every host from the next execution on.
+func (u *CheckMDNSUseCase) Subscribe(notifier ports.StateChangeNotifier) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.notifiers = append(u.notifiers, notifier)
+}
+
+// CheckType selects which records prove a host is up.
+type CheckType int
+
+const (
+	// CheckHost resolves the A/AAAA records of the host name.
+	CheckHost CheckType = iota
+	// CheckService resolves the PTR, SRV and TXT records of a DNS-SD service instance.
+	CheckService
+)
+
+func (t CheckType) String() string {
+	switch t {
+	case CheckHost:
+		return "host"
+	case CheckService:
+		return "service"
+	default:
+		return "unknown"
 	}
 }
 
+// HostConfig describes how a single host is probed.
+type HostConfig struct {
+	// Name is the host name, or the fully qualified instance name of a service check.
+	Name string
+	Type CheckType
+	// Query is how the host is queried. Its timeout bounds the whole probe, including every attempt.
+	Query ports.QueryPolicy
+	// Interval is the minimum time between two probes of the host. Zero probes the host on every execution.
+	Interval time.Duration
+	// FailThreshold is the number of consecutive failed probes before an up host is reported not up.
+	FailThreshold int
+	// SuccessThreshold is the number of consecutive successful probes before a host is reported up again.
+	SuccessThreshold int
+	// FlapThreshold is the number of changes within FlapWindow from which the host is reported flapping.
+	FlapThreshold int
+	FlapWindow    time.Duration
+	// ExpectedAddrs are the networks the host is expected to resolve into. Empty allows any address.
+	ExpectedAddrs []netip.Prefix
+	// RequireTXT makes a service check fail unless the instance advertises a TXT record.
+	RequireTXT bool
+	// TXT are the expectations on the TXT record of a service check. They imply RequireTXT.
+	TXT []TXTExpectation
+}
+
 type CheckMDNSCommand struct {
-	Hosts []string
+	Hosts []HostConfig
 }
 
 func (u *CheckMDNSUseCase) Execute(ctx context.Context, cmd CheckMDNSCommand) error {
 	var (
-		mu   sync.Mutex
-		up   = make([]string, 0, len(cmd.Hosts))
-		down = make([]string, 0, len(cmd.Hosts))
+		results = make([]ports.ProbeResult, len(cmd.Hosts))
+		probed  = make([]bool, len(cmd.Hosts))
+		now     = u.now()
 	)
 
-	g, gctx := errgroup.WithContext(ctx)
+	var wg sync.WaitGroup
 
-	for _, host := range cmd.Hosts {
-		g.Go(func() error {
-			state, err := u.probe.Probe(gctx, host, u.timeout)
-			if err != nil {
-				return fmt.Errorf("failed to probe host %s: %w", host, err)
-			}
+	for i, host := range cmd.Hosts {
+		if result, ok := u.cachedResult(host, now); ok {
+			results[i] = result
+			continue
+		}
 
-			mu.Lock()
-			defer mu.Unlock()
+		probed[i] = true
 
-			switch state {
-			case ports.HostUp:
-				up = append(up, host)
-			case ports.HostDown:
-				down = append(down, host)
-			case ports.HostUnknown:
-				return fmt.Errorf("unknown MDNS state for host %s: %d", host, state)
-			}
+		wg.Go(func() {
+			result := u.probeHost(ctx, host)
+			result.CheckedAt = now
 
-			return nil
+			results[i] = u.trackAddrs(ctx, result)
 		})
 	}
 
-	if err := g.Wait(); err != nil {
+	wg.Wait()
+
+	// A canceled cycle yields errors for every host, publishing them would only wipe out the last known state.
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	err := u.publisher.Publish(ctx, up, down)
-	if err != nil {
-		return fmt.Errorf("failed to publish mdns check results: %w", err)
+	for i, host := range cmd.Hosts {
+		if probed[i] {
+			results[i] = u.dampen(ctx, host, results[i])
+		}
+	}
+
+	changes := u.remember(cmd.Hosts, results)
+
+	return errors.Join(u.publish(ctx, results), u.notify(ctx, changes))
+}
+
+// HandleGoodbye reports the host withdrawn with a goodbye packet down right away, until its next probe.
+func (u *CheckMDNSUseCase) HandleGoodbye(ctx context.Context, goodbye ports.Goodbye) error {
+	results, change, ok := u.withdraw(goodbye)
+	if !ok {
+		return nil
+	}
+
+	u.logger.InfoContext(ctx, "Host sent goodbye",
+		slog.String("host", change.Host),
+		slog.Time("received_at", goodbye.ReceivedAt),
+	)
+
+	return errors.Join(u.publish(ctx, results), u.notify(ctx, []ports.StateChange{change}))
+}
+
+func (u *CheckMDNSUseCase) withdraw(goodbye ports.Goodbye) ([]ports.ProbeResult, ports.StateChange, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	idx := slices.IndexFunc(u.hosts, func(h HostConfig) bool {
+		return (h.Type == CheckService) == goodbye.Service && HostKey(h.Name) == HostKey(goodbye.Name)
+	})
+	if idx < 0 {
+		return nil, ports.StateChange{}, false
+	}
+
+	host := u.hosts[idx]
+	name := host.Name
+
+	last, ok := u.last[name]
+	if !ok || last.State == ports.HostDown {
+		return nil, ports.StateChange{}, false
+	}
+
+	result := ports.ProbeResult{
+		Host:       name,
+		State:      ports.HostDown,
+		CheckedAt:  goodbye.ReceivedAt,
+		KnownAddrs: last.KnownAddrs,
+	}
+
+	u.last[name] = result
+
+	flapped := false
+
+	// A goodbye is no lost answer, so the fail threshold does not apply.
+	if h, ok := u.history[name]; ok {
+		if h.up {
+			h.up = false
+			h.changes = changesSince(append(h.changes, goodbye.ReceivedAt), goodbye.ReceivedAt.Add(-host.FlapWindow))
+			flapped = true
+		}
+
+		h.reported = result
+		h.streak = 0
+	}
+
+	results := make([]ports.ProbeResult, 0, len(u.hosts))
+
+	for _, h := range u.hosts {
+		r := u.last[h.Name]
+		r.Reused = h.Name != name
+		r.Flapped = h.Name == name && flapped
+
+		results = append(results, r)
+	}
+
+	change := ports.StateChange{
+		Host:      name,
+		Previous:  last.State,
+		Current:   ports.HostDown,
+		ChangedAt: goodbye.ReceivedAt,
+	}
+
+	return results, change, true
+}
+
+func (u *CheckMDNSUseCase) publish(ctx context.Context, results []ports.ProbeResult) error {
+	var errs []error
+
+	for _, p := range u.publishers {
+		if err := p.Publish(ctx, results); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish mdns check results: %w", errors.Join(errs...))
 	}
 
 	return nil
 }
+
+func (u *CheckMDNSUseCase) notify(ctx context.Context, changes []ports.StateChange) error {
+	u.mu.Lock()
+	notifiers := u.notifiers
+	u.mu.Unlock()
+
+	var errs []error
+
+	for _, change := range changes {
+		u.logger.InfoContext(ctx, "Host state changed",
+			slog.String("host", change.Host),
+			slog.String("previous", change.Previous.String()),
+			slog.String("current", change.Current.String()),
+			slog.String("reason", change.Reason),
+		)
+
+		for _, n := range notifiers {
+			if err := n.Notify(ctx, change); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to notify host state changes: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (u *CheckMDNSUseCase) cachedResult(host HostConfig, now time.Time) (ports.ProbeResult, bool) {
+	if host.Interval <= 0 {
+		return ports.ProbeResult{}, false
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	last, ok := u.last[host.Name]
+	if !ok || now.Sub(last.CheckedAt) >= host.Interval-host.Interval/dueSlackDivisor {
+		return ports.ProbeResult{}, false
+	}
+
+	last.Reused = true
+
+	return last, true
+}
+
+func (u *CheckMDNSUseCase) remember(hosts []HostConfig, results []ports.ProbeResult) []ports.StateChange {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.hosts = hosts
+
+	last := make(map[string]ports.ProbeResult, len(results))
+	known := make(map[string][]netip.Addr, len(results))
+	history := make(map[string]*hostHistory, len(results))
+
+	var changes []ports.StateChange
+
+	for i, r := range results {
+		// A goodbye handled during the execution is more recent than the result, and was published already.
+		if prev, ok := u.last[r.Host]; ok && prev.CheckedAt.After(r.CheckedAt) {
+			prev.Reused = true
+			results[i] = prev
+			r = prev
+		}
+
+		// A host is unknown until its first result, and again once it is checked after it was removed.
+		if prev := u.last[r.Host].State; prev != r.State {
+			changes = append(changes, ports.StateChange{
+				Host:      r.Host,
+				Previous:  prev,
+				Current:   r.State,
+				ChangedAt: r.CheckedAt,
+				Reason:    r.Reason,
+			})
+		}
+
+		r.AddrChange = nil
+		r.Flapped = false
+		r.Reused = false
+		last[r.Host] = r
+
+		if addrs, ok := u.known[r.Host]; ok {
+			known[r.Host] = addrs
+		}
+
+		if h, ok := u.history[r.Host]; ok {
+			history[r.Host] = h
+		}
+	}
+
+	u.last = last
+	u.known = known
+	u.history = history
+
+	return changes
+}
+
+func (u *CheckMDNSUseCase) probeHost(ctx context.Context, host HostConfig) ports.ProbeResult {
+	result, err := u.runProbe(ctx, host)
+
+	if err == nil && result.State != ports.HostUp && result.State != ports.HostDown {
+		err = &ports.ProbeError{
+			Class: ports.ErrorClassInvalidState,
+			Err:   fmt.Errorf("unknown MDNS state: %d", result.State),
+		}
+	}
+
+	if err != nil {
+		class := ports.ErrorClassOther
+
+		var probeErr *ports.ProbeError
+		if errors.As(err, &probeErr) {
+			class = probeErr.Class
+		}
+
+		u.logger.WarnContext(ctx, "Failed to probe host",
+			slog.String("host", host.Name),
+			slog.String("error_class", class.String()),
+			logging.Error(err),
+		)
+
+		return ports.ProbeResult{Host: host.Name, State: ports.HostError, ErrorClass: class, Err: err}
+	}
+
+	result.Host = host.Name
+
+	if result.State == ports.HostUp && len(host.TXT) > 0 {
+		if reason, detail := checkTXT(result.Service, host.TXT); reason != "" {
+			result.State = ports.HostDegraded
+			result.Reason = reason
+
+			u.logger.WarnContext(ctx, "Service advertises unexpected TXT record",
+				slog.String("host", host.Name),
+				slog.String("reason", reason),
+				slog.String("detail", detail),
+			)
+		}
+	}
+
+	if result.State.Answered() {
+		result = u.verifyAddrs(ctx, host, result)
+	}
+
+	u.logger.DebugContext(ctx, "Probed host",
+		slog.String("host", host.Name),
+		slog.String("state", result.State.String()),
+		slog.Duration("rtt", result.RTT),
+		slog.Any("addrs", result.Addrs),
+		slog.String("family", result.Family.String()),
+		slog.Int("attempts", result.Attempts),
+		slog.Any("sources", result.Sources),
+	)
+
+	return result
+}
+
+func (u *CheckMDNSUseCase) runProbe(ctx context.Context, host HostConfig) (ports.ProbeResult, error) {
+	switch host.Type {
+	case CheckHost:
+		return u.probe.Probe(ctx, host.Name, host.Query)
+	case CheckService:
+		check := ports.ServiceCheck{Instance: host.Name, RequireTXT: host.RequireTXT || len(host.TXT) > 0}
+		return u.serviceProbe.ProbeService(ctx, check, host.Query)
+	default:
+		return ports.ProbeResult{}, fmt.Errorf("unknown check type: %d", host.Type)
+	}
+}
+
+// HostKey returns the key telling hosts apart, ignoring the case, the trailing dot and the ".local" domain.
+func HostKey(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	return strings.TrimSuffix(name, ".local")
+}
+
+func unexpectedAddrs(addrs []netip.Addr, expected []netip.Prefix) []netip.Addr {
+	if len(expected) == 0 {
+		return nil
+	}
+
+	var unexpected []netip.Addr
+
+	for _, addr := range addrs {
+		if !addrInPrefixes(addr, expected) {
+			unexpected = append(unexpected, addr)
+		}
+	}
+
+	return unexpected
+}
+
+func addrInPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	addr = addr.Unmap().WithZone("")
+
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,9 +1,13 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
+	"net/netip"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,62 +18,131 @@ import (
 	portsm "github.com/khmm12/mdns-health-checker/internal/ports/mocks"
 )
 
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
 func TestCheckMDNSUseCase_CountsUpAndDownState(t *testing.T) {
 	ctx := t.Context()
 
 	probe := portsm.NewMockMDNSProbe(t)
 	publisher := portsm.NewMockMDNSStatePublisher(t)
 
-	uc := newTestCheckMDNSUseCase(t, probe, publisher)
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	addr := netip.MustParseAddr("192.168.1.10")
 
-	probe.On("Probe", mock.Anything, "printer1.local", 10*time.Second).Return(ports.HostUp, nil)
-	probe.On("Probe", mock.Anything, "printer2.local", 10*time.Second).Return(ports.HostDown, nil)
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).Return(ports.ProbeResult{
+		State:  ports.HostUp,
+		RTT:    15 * time.Millisecond,
+		Addrs:  []netip.Addr{addr},
+		Family: ports.FamilyIPv4,
+	}, nil)
+	probe.On("Probe", mock.Anything, "printer2.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostDown}, nil)
 
-	publisher.On("Publish", mock.Anything, []string{"printer1.local"}, []string{"printer2.local"}).Return(nil)
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{
+			Host:       "printer1.local",
+			CheckedAt:  testNow,
+			State:      ports.HostUp,
+			RTT:        15 * time.Millisecond,
+			Addrs:      []netip.Addr{addr},
+			Family:     ports.FamilyIPv4,
+			KnownAddrs: []netip.Addr{addr},
+		},
+		{Host: "printer2.local", CheckedAt: testNow, State: ports.HostDown},
+	}).Return(nil)
 
 	err := uc.Execute(ctx, CheckMDNSCommand{
-		Hosts: []string{"printer1.local", "printer2.local"},
+		Hosts: testHosts("printer1.local", "printer2.local"),
 	})
 
 	require.NoError(t, err)
 }
 
-func TestCheckMDNSUseCase_BubblesUpProbeError(t *testing.T) {
+func TestCheckMDNSUseCase_PublishesProbeErrorAsErrorState(t *testing.T) {
 	ctx := t.Context()
 
 	probe := portsm.NewMockMDNSProbe(t)
 	publisher := portsm.NewMockMDNSStatePublisher(t)
 
-	uc := newTestCheckMDNSUseCase(t, probe, publisher)
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	probeErr := &ports.ProbeError{Class: ports.ErrorClassClosed, Err: errors.New("connection is closed")}
+	otherErr := errors.New("probe failed")
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).Return(ports.ProbeResult{}, probeErr)
+	probe.On("Probe", mock.Anything, "printer2.local", testQuery).Return(ports.ProbeResult{}, otherErr)
+	probe.On("Probe", mock.Anything, "printer3.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil)
 
-	probe.On("Probe", mock.Anything, "printer1.local", 10*time.Second).
-		Return(ports.HostUnknown, errors.New("probe failed"))
-	probe.On("Probe", mock.Anything, "printer2.local", 10*time.Second).Return(ports.HostUp, nil)
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{
+			Host:       "printer1.local",
+			CheckedAt:  testNow,
+			State:      ports.HostError,
+			ErrorClass: ports.ErrorClassClosed,
+			Err:        probeErr,
+		},
+		{
+			Host:       "printer2.local",
+			CheckedAt:  testNow,
+			State:      ports.HostError,
+			ErrorClass: ports.ErrorClassOther,
+			Err:        otherErr,
+		},
+		{Host: "printer3.local", CheckedAt: testNow, State: ports.HostUp},
+	}).Return(nil)
 
 	err := uc.Execute(ctx, CheckMDNSCommand{
-		Hosts: []string{"printer1.local", "printer2.local"},
+		Hosts: testHosts("printer1.local", "printer2.local", "printer3.local"),
 	})
 
-	require.ErrorContains(t, err, "failed to probe host")
-	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+	require.NoError(t, err)
 }
 
-func TestCheckMDNSUseCase_FailsOnUnknownState(t *testing.T) {
+func TestCheckMDNSUseCase_PublishesUnknownStateAsErrorState(t *testing.T) {
 	ctx := t.Context()
 
 	probe := portsm.NewMockMDNSProbe(t)
 	publisher := portsm.NewMockMDNSStatePublisher(t)
 
-	uc := newTestCheckMDNSUseCase(t, probe, publisher)
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).Return(ports.ProbeResult{}, nil)
+
+	publisher.On("Publish", mock.Anything, mock.MatchedBy(func(results []ports.ProbeResult) bool {
+		return len(results) == 1 &&
+			results[0].State == ports.HostError &&
+			results[0].ErrorClass == ports.ErrorClassInvalidState &&
+			results[0].Err != nil &&
+			strings.Contains(results[0].Err.Error(), "unknown MDNS state")
+	})).Return(nil)
+
+	err := uc.Execute(ctx, CheckMDNSCommand{
+		Hosts: testHosts("printer1.local"),
+	})
+
+	require.NoError(t, err)
+}
+
+func TestCheckMDNSUseCase_SkipsPublishingWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
 
-	probe.On("Probe", mock.Anything, "printer1.local", 10*time.Second).Return(ports.HostUnknown, nil)
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(ports.ProbeResult{}, context.Canceled)
 
 	err := uc.Execute(ctx, CheckMDNSCommand{
-		Hosts: []string{"printer1.local"},
+		Hosts: testHosts("printer1.local"),
 	})
 
-	require.ErrorContains(t, err, "unknown MDNS state for host")
-	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+	require.ErrorIs(t, err, context.Canceled)
+	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
 }
 
 func TestCheckMDNSUseCase_ReturnsErrorWhenPublishingFails(t *testing.T) {
@@ -78,32 +151,671 @@ func TestCheckMDNSUseCase_ReturnsErrorWhenPublishingFails(t *testing.T) {
 	probe := portsm.NewMockMDNSProbe(t)
 	publisher := portsm.NewMockMDNSStatePublisher(t)
 
-	uc := newTestCheckMDNSUseCase(t, probe, publisher)
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
 
-	probe.On("Probe", mock.Anything, "printer1.local", 10*time.Second).Return(ports.HostUp, nil)
-	probe.On("Probe", mock.Anything, "printer2.local", 10*time.Second).Return(ports.HostDown, nil)
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil)
+	probe.On("Probe", mock.Anything, "printer2.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostDown}, nil)
 
-	publisher.On("Publish", mock.Anything, []string{"printer1.local"}, []string{"printer2.local"}).
-		Return(errors.New("publish failed"))
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("publish failed"))
 
 	err := uc.Execute(ctx, CheckMDNSCommand{
-		Hosts: []string{"printer1.local", "printer2.local"},
+		Hosts: testHosts("printer1.local", "printer2.local"),
 	})
 
 	require.ErrorContains(t, err, "failed to publish mdns check results")
 }
 
+func TestCheckMDNSUseCase_ProbesWithQueryPolicyOfHost(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	serviceProbe := portsm.NewMockMDNSServiceProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, serviceProbe, publisher)
+
+	policy := ports.QueryPolicy{
+		Timeout:         5 * time.Second,
+		Attempts:        3,
+		AttemptInterval: time.Second,
+		AnswerWindow:    250 * time.Millisecond,
+	}
+
+	probe.On("Probe", mock.Anything, "printer1.local", policy).
+		Return(ports.ProbeResult{State: ports.HostUp, Attempts: 2}, nil)
+	serviceProbe.On("ProbeService", mock.Anything, ports.ServiceCheck{Instance: "Bridge._hap._tcp.local"}, policy).
+		Return(ports.ProbeResult{State: ports.HostDown, Attempts: 3}, nil)
+
+	// The attempts of the probe are reported as they are, the host is not probed again.
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: testNow, State: ports.HostUp, Attempts: 2},
+		{Host: "Bridge._hap._tcp.local", CheckedAt: testNow, State: ports.HostDown, Attempts: 3},
+	}).Return(nil)
+
+	err := uc.Execute(ctx, CheckMDNSCommand{
+		Hosts: []HostConfig{
+			{Name: "printer1.local", Query: policy},
+			{Name: "Bridge._hap._tcp.local", Type: CheckService, Query: policy},
+		},
+	})
+
+	require.NoError(t, err)
+	probe.AssertNumberOfCalls(t, "Probe", 1)
+	serviceProbe.AssertNumberOfCalls(t, "ProbeService", 1)
+}
+
+func TestCheckMDNSUseCase_ReusesResultUntilHostIsDue(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Once()
+	probe.On("Probe", mock.Anything, "printer2.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostDown}, nil).Twice()
+
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: testNow, State: ports.HostUp},
+		{Host: "printer2.local", CheckedAt: testNow, State: ports.HostDown},
+	}).Return(nil).Once()
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: testNow, State: ports.HostUp, Reused: true},
+		{Host: "printer2.local", CheckedAt: testNow, State: ports.HostDown},
+	}).Return(nil).Once()
+
+	cmd := CheckMDNSCommand{
+		Hosts: []HostConfig{
+			{Name: "printer1.local", Query: testQuery, Interval: time.Hour},
+			{Name: "printer2.local", Query: testQuery},
+		},
+	}
+
+	require.NoError(t, uc.Execute(ctx, cmd))
+	require.NoError(t, uc.Execute(ctx, cmd))
+}
+
+func TestCheckMDNSUseCase_ProbesServiceInstances(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	serviceProbe := portsm.NewMockMDNSServiceProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, serviceProbe, publisher)
+
+	bridge := &ports.ServiceInstance{Instance: "Bridge", Service: "_hap._tcp.local", Host: "bridge.local", Port: 51826}
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil)
+	serviceProbe.On("ProbeService", mock.Anything, ports.ServiceCheck{
+		Instance:   "Bridge._hap._tcp.local",
+		RequireTXT: true,
+	}, testQuery).Return(ports.ProbeResult{State: ports.HostUp, Service: bridge}, nil)
+
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: testNow, State: ports.HostUp},
+		{Host: "Bridge._hap._tcp.local", CheckedAt: testNow, State: ports.HostUp, Service: bridge},
+	}).Return(nil)
+
+	err := uc.Execute(ctx, CheckMDNSCommand{
+		Hosts: []HostConfig{
+			{Name: "printer1.local", Query: testQuery},
+			{Name: "Bridge._hap._tcp.local", Type: CheckService, Query: testQuery, RequireTXT: true},
+		},
+	})
+
+	require.NoError(t, err)
+}
+
+func TestCheckMDNSUseCase_DegradesServiceFailingTXTExpectations(t *testing.T) {
+	ctx := t.Context()
+
+	serviceProbe := portsm.NewMockMDNSServiceProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, nil, serviceProbe, publisher)
+
+	bridge := &ports.ServiceInstance{Instance: "Bridge", Service: "_hap._tcp.local", Text: []string{"sf=1"}}
+
+	serviceProbe.On("ProbeService", mock.Anything, ports.ServiceCheck{
+		Instance:   "Bridge._hap._tcp.local",
+		RequireTXT: true,
+	}, testQuery).Return(ports.ProbeResult{State: ports.HostUp, RTT: time.Millisecond, Service: bridge}, nil)
+
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{
+			Host:      "Bridge._hap._tcp.local",
+			CheckedAt: testNow,
+			State:     ports.HostDegraded,
+			RTT:       time.Millisecond,
+			Service:   bridge,
+			Reason:    "txt_value_mismatch:sf",
+		},
+	}).Return(nil)
+
+	err := uc.Execute(ctx, CheckMDNSCommand{
+		Hosts: []HostConfig{{
+			Name:  "Bridge._hap._tcp.local",
+			Type:  CheckService,
+			Query: testQuery,
+			TXT:   []TXTExpectation{{Key: "sf", Value: regexp.MustCompile(`^(?:0)$`)}},
+		}},
+	})
+
+	require.NoError(t, err)
+}
+
+func TestCheckMDNSUseCase_ReportsMismatchedHosts(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	var (
+		pinned   = netip.MustParseAddr("192.168.1.10")
+		spoofed  = netip.MustParseAddr("192.168.1.66")
+		linkAddr = netip.MustParseAddr("fe80::1")
+	)
+
+	answer := func(addr netip.Addr, sources ...netip.Addr) ports.ProbeResult {
+		return ports.ProbeResult{State: ports.HostUp, Addrs: []netip.Addr{addr}, Sources: sources}
+	}
+
+	probe.On("Probe", mock.Anything, "pinned.local", testQuery).Return(answer(pinned, pinned), nil)
+	probe.On("Probe", mock.Anything, "moved.local", testQuery).Return(answer(spoofed, spoofed), nil)
+	probe.On("Probe", mock.Anything, "claimed.local", testQuery).Return(answer(pinned, pinned, spoofed), nil)
+	probe.On("Probe", mock.Anything, "dualstack.local", testQuery).Return(answer(pinned, pinned, linkAddr), nil)
+
+	var published []ports.ProbeResult
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = args.Get(1).([]ports.ProbeResult)
+	}).Return(nil)
+
+	hosts := testHosts("pinned.local", "moved.local", "claimed.local", "dualstack.local")
+	for i := range hosts[:2] {
+		hosts[i].ExpectedAddrs = []netip.Prefix{netip.MustParsePrefix("192.168.1.0/28")}
+	}
+
+	require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+	require.Len(t, published, 4)
+
+	require.Equal(t, ports.HostUp, published[0].State)
+
+	require.Equal(t, ports.HostMismatch, published[1].State)
+	require.Equal(t, "unexpected_address", published[1].Reason)
+
+	require.Equal(t, ports.HostMismatch, published[2].State)
+	require.Equal(t, "multiple_responders", published[2].Reason)
+	require.True(t, published[2].Conflict)
+
+	// A dual-stack host answers from an address of each family.
+	require.Equal(t, ports.HostUp, published[3].State)
+	require.Empty(t, published[3].Reason)
+	require.False(t, published[3].Conflict)
+}
+
+func TestCheckMDNSUseCase_PublishesGoodbyeImmediately(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	serviceProbe := portsm.NewMockMDNSServiceProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, serviceProbe, publisher)
+
+	goodbyeAt := testNow.Add(time.Minute)
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Once()
+	serviceProbe.On("ProbeService", mock.Anything, ports.ServiceCheck{Instance: "Bridge._hap._tcp"}, testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Once()
+
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: testNow, State: ports.HostUp},
+		{Host: "Bridge._hap._tcp", CheckedAt: testNow, State: ports.HostUp},
+	}).Return(nil).Once()
+	// Only the withdrawn host is new, the other ones were published with the previous execution.
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: goodbyeAt, State: ports.HostDown, Flapped: true},
+		{Host: "Bridge._hap._tcp", CheckedAt: testNow, State: ports.HostUp, Reused: true},
+	}).Return(nil).Once()
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: goodbyeAt, State: ports.HostDown, Reused: true},
+		{Host: "Bridge._hap._tcp", CheckedAt: testNow, State: ports.HostUp, Reused: true},
+	}).Return(nil).Once()
+	publisher.On("Publish", mock.Anything, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: goodbyeAt, State: ports.HostDown, Reused: true},
+		{Host: "Bridge._hap._tcp", CheckedAt: goodbyeAt, State: ports.HostDown, Flapped: true},
+	}).Return(nil).Once()
+
+	cmd := CheckMDNSCommand{
+		Hosts: []HostConfig{
+			{Name: "printer1.local", Query: testQuery, Interval: time.Hour},
+			{Name: "Bridge._hap._tcp", Type: CheckService, Query: testQuery, Interval: time.Hour},
+		},
+	}
+
+	require.NoError(t, uc.Execute(ctx, cmd))
+
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "Printer1.local", ReceivedAt: goodbyeAt}))
+
+	// Down hosts, unknown hosts and names of the other check type are ignored.
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "printer1.local", ReceivedAt: goodbyeAt}))
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "nas.local", ReceivedAt: goodbyeAt}))
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "bridge._hap._tcp.local", ReceivedAt: goodbyeAt}))
+
+	// The host stays down until it is due for a probe.
+	require.NoError(t, uc.Execute(ctx, cmd))
+
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{
+		Name:       "bridge._hap._tcp.local",
+		Service:    true,
+		ReceivedAt: goodbyeAt,
+	}))
+}
+
+func TestCheckMDNSUseCase_KeepsGoodbyeOfCachedHostDuringExecution(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+	notifier := portsm.NewMockStateChangeNotifier(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+	uc.Subscribe(notifier)
+
+	var (
+		goodbyeAt = testNow.Add(time.Minute)
+		laterAt   = testNow.Add(2 * time.Minute)
+		probing   = make(chan struct{})
+		release   = make(chan struct{})
+	)
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Once()
+	probe.On("Probe", mock.Anything, "printer2.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Once()
+	probe.On("Probe", mock.Anything, "printer2.local", testQuery).
+		Run(func(mock.Arguments) {
+			close(probing)
+			<-release
+		}).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Once()
+
+	var (
+		published [][]ports.ProbeResult
+		changes   []ports.StateChange
+	)
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).([]ports.ProbeResult))
+	}).Return(nil)
+	notifier.On("Notify", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		changes = append(changes, args.Get(1).(ports.StateChange))
+	}).Return(nil)
+
+	cmd := CheckMDNSCommand{
+		Hosts: []HostConfig{
+			{Name: "printer1.local", Query: testQuery, Interval: time.Hour},
+			{Name: "printer2.local", Query: testQuery},
+		},
+	}
+
+	require.NoError(t, uc.Execute(ctx, cmd))
+
+	uc.now = func() time.Time { return laterAt }
+
+	done := make(chan error)
+
+	go func() { done <- uc.Execute(ctx, cmd) }()
+
+	// The goodbye arrives after the execution took the cached result of printer1.local.
+	<-probing
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "printer1.local", ReceivedAt: goodbyeAt}))
+	close(release)
+	require.NoError(t, <-done)
+
+	require.Len(t, published, 3)
+	require.Equal(t, []ports.ProbeResult{
+		{Host: "printer1.local", CheckedAt: goodbyeAt, State: ports.HostDown, Reused: true},
+		{Host: "printer2.local", CheckedAt: laterAt, State: ports.HostUp},
+	}, published[2])
+
+	// The execution does not bring the host back up.
+	require.Len(t, changes, 3)
+	require.Equal(t, ports.StateChange{
+		Host:      "printer1.local",
+		Previous:  ports.HostUp,
+		Current:   ports.HostDown,
+		ChangedAt: goodbyeAt,
+	}, changes[2])
+}
+
+func TestCheckMDNSUseCase_TracksAddressChanges(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	var (
+		v4Old = netip.MustParseAddr("192.168.1.10")
+		v4New = netip.MustParseAddr("192.168.1.20")
+		v6    = netip.MustParseAddr("fe80::1")
+	)
+
+	answer := func(addr netip.Addr) ports.ProbeResult {
+		return ports.ProbeResult{State: ports.HostUp, Addrs: []netip.Addr{addr}}
+	}
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).Return(answer(v4Old), nil).Once()
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).Return(answer(v6), nil).Once()
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostDown}, nil).Once()
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).Return(answer(v4New), nil).Once()
+
+	var published []ports.ProbeResult
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).([]ports.ProbeResult)...)
+	}).Return(nil)
+
+	for range 4 {
+		require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: testHosts("printer1.local")}))
+	}
+
+	require.Len(t, published, 4)
+
+	// The first address of a family, or an answer of another family, is not a change.
+	require.Nil(t, published[0].AddrChange)
+	require.Equal(t, []netip.Addr{v4Old}, published[0].KnownAddrs)
+	require.Nil(t, published[1].AddrChange)
+	require.Equal(t, []netip.Addr{v4Old, v6}, published[1].KnownAddrs)
+
+	// The known addresses are kept while the host is down.
+	require.Nil(t, published[2].AddrChange)
+	require.Equal(t, []netip.Addr{v4Old, v6}, published[2].KnownAddrs)
+
+	require.Equal(t, &ports.AddrChange{
+		Previous: []netip.Addr{v4Old, v6},
+		Current:  []netip.Addr{v4New, v6},
+	}, published[3].AddrChange)
+	require.Equal(t, []netip.Addr{v4New, v6}, published[3].KnownAddrs)
+}
+
 func newTestCheckMDNSUseCase(
 	t *testing.T,
 	probe ports.MDNSProbe,
+	serviceProbe ports.MDNSServiceProbe,
 	publisher ports.MDNSStatePublisher,
 ) *CheckMDNSUseCase {
 	t.Helper()
 
-	return NewCheckMDNSUseCase(
+	uc := NewCheckMDNSUseCase(
 		slog.New(slog.NewTextHandler(io.Discard, nil)),
 		probe,
+		serviceProbe,
 		publisher,
-		10*time.Second,
 	)
+	uc.now = func() time.Time { return testNow }
+
+	return uc
+}
+
+// testQuery is the query policy of the test hosts.
+var testQuery = ports.QueryPolicy{Timeout: 10 * time.Second}
+
+func testHosts(names ...string) []HostConfig {
+	hosts := make([]HostConfig, 0, len(names))
+	for _, name := range names {
+		hosts = append(hosts, HostConfig{Name: name, Query: testQuery})
+	}
+
+	return hosts
+}
+
+func TestCheckMDNSUseCase_AppliesStateThresholds(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	states := []ports.HostState{
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostDown,
+		ports.HostError,
+		ports.HostUp,
+		ports.HostUp,
+	}
+
+	for _, s := range states {
+		probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+			Return(ports.ProbeResult{State: s}, nil).Once()
+	}
+
+	var published []ports.ProbeResult
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).([]ports.ProbeResult)...)
+	}).Return(nil)
+
+	hosts := []HostConfig{
+		{Name: "printer1.local", Query: testQuery, FailThreshold: 2, SuccessThreshold: 2},
+	}
+
+	for range states {
+		require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+	}
+
+	require.Len(t, published, len(states))
+
+	// A single lost answer keeps the host up, a second one in a row takes it down. A change between states which are
+	// not up is reported right away.
+	reported := make([]ports.HostState, 0, len(published))
+	flapped := make([]bool, 0, len(published))
+	reused := make([]bool, 0, len(published))
+
+	for _, r := range published {
+		reported = append(reported, r.State)
+		flapped = append(flapped, r.Flapped)
+		reused = append(reused, r.Reused)
+	}
+
+	require.Equal(t, []ports.HostState{
+		ports.HostUp,
+		ports.HostUp,
+		ports.HostUp,
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostError,
+		ports.HostError,
+		ports.HostUp,
+	}, reported)
+	require.Equal(t, []bool{false, true, true, true, false, false, true, false}, flapped)
+	// A held result repeats the probe it was reported with.
+	require.Equal(t, []bool{false, true, false, true, false, false, true, false}, reused)
+}
+
+func TestCheckMDNSUseCase_ReportsFlappingHosts(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	states := []ports.HostState{
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostDown,
+		ports.HostUp,
+	}
+
+	for _, s := range states {
+		probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+			Return(ports.ProbeResult{State: s}, nil).Once()
+	}
+
+	var published []ports.ProbeResult
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).([]ports.ProbeResult)...)
+	}).Return(nil)
+
+	hosts := []HostConfig{
+		{Name: "printer1.local", Query: testQuery, FlapThreshold: 3, FlapWindow: 10 * time.Minute},
+	}
+
+	// The last probe runs once the first changes left the flap window.
+	offsets := []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 13 * time.Minute}
+
+	for _, offset := range offsets {
+		uc.now = func() time.Time { return testNow.Add(offset) }
+
+		require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+	}
+
+	reported := make([]ports.HostState, 0, len(published))
+	for _, r := range published {
+		reported = append(reported, r.State)
+	}
+
+	require.Equal(t, []ports.HostState{
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostUp,
+		ports.HostFlapping,
+		ports.HostFlapping,
+		ports.HostUp,
+	}, reported)
+}
+
+func TestCheckMDNSUseCase_CountsGoodbyesAsFlaps(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Times(3)
+
+	var published []ports.ProbeResult
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).([]ports.ProbeResult)...)
+	}).Return(nil)
+
+	hosts := []HostConfig{
+		{Name: "printer1.local", Query: testQuery, FlapThreshold: 4, FlapWindow: 10 * time.Minute},
+	}
+
+	// The host answers every probe, but sends a goodbye in between.
+	for i := range 3 {
+		uc.now = func() time.Time { return testNow.Add(time.Duration(2*i) * time.Minute) }
+
+		require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+
+		if i < 2 {
+			goodbyeAt := testNow.Add(time.Duration(2*i+1) * time.Minute)
+			require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "printer1.local", ReceivedAt: goodbyeAt}))
+		}
+	}
+
+	reported := make([]ports.HostState, 0, len(published))
+	flapped := make([]bool, 0, len(published))
+
+	for _, r := range published {
+		reported = append(reported, r.State)
+		flapped = append(flapped, r.Flapped)
+	}
+
+	require.Equal(t, []ports.HostState{
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostUp,
+		ports.HostDown,
+		ports.HostFlapping,
+	}, reported)
+	require.Equal(t, []bool{false, true, true, true, true}, flapped)
+}
+
+func TestCheckMDNSUseCase_NotifiesStateChanges(t *testing.T) {
+	ctx := t.Context()
+
+	probe := portsm.NewMockMDNSProbe(t)
+	publisher := portsm.NewMockMDNSStatePublisher(t)
+	notifier := portsm.NewMockStateChangeNotifier(t)
+
+	uc := newTestCheckMDNSUseCase(t, probe, nil, publisher)
+	uc.Subscribe(notifier)
+
+	var (
+		goodbyeAt = testNow.Add(time.Minute)
+		laterAt   = testNow.Add(2 * time.Minute)
+	)
+
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp}, nil).Times(3)
+	probe.On("Probe", mock.Anything, "printer1.local", testQuery).
+		Return(ports.ProbeResult{State: ports.HostUp, Addrs: []netip.Addr{netip.MustParseAddr("10.0.0.1")}}, nil).Once()
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	notifier.On("Notify", mock.Anything, ports.StateChange{
+		Host:      "printer1.local",
+		Previous:  ports.HostUnknown,
+		Current:   ports.HostUp,
+		ChangedAt: testNow,
+	}).Return(nil).Once()
+	notifier.On("Notify", mock.Anything, ports.StateChange{
+		Host:      "printer1.local",
+		Previous:  ports.HostUp,
+		Current:   ports.HostDown,
+		ChangedAt: goodbyeAt,
+	}).Return(nil).Once()
+	notifier.On("Notify", mock.Anything, ports.StateChange{
+		Host:      "printer1.local",
+		Previous:  ports.HostDown,
+		Current:   ports.HostMismatch,
+		ChangedAt: laterAt,
+		Reason:    "unexpected_address",
+	}).Return(errors.New("queue full")).Once()
+
+	hosts := []HostConfig{{
+		Name:          "printer1.local",
+		Query:         testQuery,
+		ExpectedAddrs: []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")},
+	}}
+
+	// A host staying in its state is no change.
+	require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+	require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+
+	require.NoError(t, uc.HandleGoodbye(ctx, ports.Goodbye{Name: "printer1.local", ReceivedAt: goodbyeAt}))
+
+	// A cycle started before the goodbye does not bring the host back up.
+	require.NoError(t, uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts}))
+
+	uc.now = func() time.Time { return laterAt }
+
+	err := uc.Execute(ctx, CheckMDNSCommand{Hosts: hosts})
+	require.ErrorContains(t, err, "failed to notify host state changes: queue full")
 }
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+type hostHistory struct {
+	reported ports.ProbeResult
+	up       bool
+	streak   int
+	changes  []time.Time
+	flapping bool
+}
+
+// dampen applies the thresholds and the flap detection of the host to a fresh result. Changes between states which
+// are not up, e.g. from down to error, are reported right away.
+func (u *CheckMDNSUseCase) dampen(ctx context.Context, host HostConfig, result ports.ProbeResult) ports.ProbeResult {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if last, ok := u.last[host.Name]; ok && last.CheckedAt.After(result.CheckedAt) {
+		last.Reused = true
+
+		return last
+	}
+
+	up := result.State == ports.HostUp
+
+	h, ok := u.history[host.Name]
+	if !ok {
+		u.history[host.Name] = &hostHistory{reported: result, up: up}
+		return result
+	}
+
+	if up != h.up {
+		result.Flapped = true
+		h.changes = append(h.changes, result.CheckedAt)
+	}
+
+	h.up = up
+	h.changes = changesSince(h.changes, result.CheckedAt.Add(-host.FlapWindow))
+
+	reported := u.applyThresholds(ctx, host, h, result)
+
+	flapping := host.FlapThreshold > 0 && len(h.changes) >= host.FlapThreshold
+
+	switch {
+	case flapping && !h.flapping:
+		u.logger.WarnContext(ctx, "Host is flapping",
+			slog.String("host", host.Name),
+			slog.Int("changes", len(h.changes)),
+			slog.Duration("window", host.FlapWindow),
+		)
+	case !flapping && h.flapping:
+		u.logger.InfoContext(ctx, "Host stopped flapping", slog.String("host", host.Name))
+	}
+
+	h.flapping = flapping
+
+	if flapping {
+		reported.State = ports.HostFlapping
+		reported.Reason = ""
+	}
+
+	return reported
+}
+
+func (u *CheckMDNSUseCase) applyThresholds(
+	ctx context.Context,
+	host HostConfig,
+	h *hostHistory,
+	result ports.ProbeResult,
+) ports.ProbeResult {
+	if (h.reported.State == ports.HostUp) == h.up {
+		h.streak = 0
+		h.reported = result
+
+		return result
+	}
+
+	threshold := host.FailThreshold
+	if h.up {
+		threshold = host.SuccessThreshold
+	}
+
+	h.streak++
+
+	if h.streak >= threshold {
+		h.streak = 0
+		h.reported = result
+
+		return result
+	}
+
+	u.logger.DebugContext(ctx, "Holding state of host until threshold is reached",
+		slog.String("host", host.Name),
+		slog.String("state", h.reported.State.String()),
+		slog.String("probed_state", result.State.String()),
+		slog.Int("streak", h.streak),
+		slog.Int("threshold", threshold),
+	)
+
+	// The held result is reported as of this probe, with the round-trip time of the probe it was reported with.
+	held := h.reported
+	held.Reused = true
+	held.CheckedAt = result.CheckedAt
+	held.KnownAddrs = result.KnownAddrs
+	held.AddrChange = result.AddrChange
+	held.Flapped = result.Flapped
+
+	return held
+}
+
+func changesSince(changes []time.Time, since time.Time) []time.Time {
+	for i, at := range changes {
+		if !at.Before(since) {
+			return changes[i:]
+		}
+	}
+
+	return nil
+}
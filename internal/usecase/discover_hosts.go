@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// DiscoverHostsUseCase browses DNS-SD service types and keeps track of the hosts advertising them.
+type DiscoverHostsUseCase struct {
+	logger  *slog.Logger
+	browser ports.ServiceBrowser
+	now     func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]DiscoveredHost
+}
+
+func NewDiscoverHostsUseCase(logger *slog.Logger, browser ports.ServiceBrowser) *DiscoverHostsUseCase {
+	return &DiscoverHostsUseCase{
+		logger:  logger,
+		browser: browser,
+		now:     time.Now,
+		hosts:   make(map[string]DiscoveredHost),
+	}
+}
+
+// DiscoveredHost is a host found advertising one of the browsed service types.
+type DiscoveredHost struct {
+	Name string
+	// Services are the fully qualified instance names the host advertised when it was last seen.
+	Services []string
+	// FirstSeen is when the host was discovered.
+	FirstSeen time.Time
+	// LastSeen is when the host last answered a browse query.
+	LastSeen time.Time
+}
+
+// HostFilter selects discovered hosts by their names with path.Match glob patterns.
+type HostFilter struct {
+	// Include are the patterns a host has to match any of. Empty includes every host.
+	Include []string
+	// Exclude are the patterns of hosts to skip, taking precedence over Include.
+	Exclude []string
+}
+
+// Match reports whether the host passes the filter. Malformed patterns never match.
+func (f HostFilter) Match(host string) bool {
+	if matchAny(f.Exclude, host) {
+		return false
+	}
+
+	return len(f.Include) == 0 || matchAny(f.Include, host)
+}
+
+type DiscoverHostsCommand struct {
+	// Services are the service types to browse, e.g. "_hap._tcp".
+	Services []string
+	// Timeout is how long answers are collected for each service type.
+	Timeout time.Duration
+	Filter  HostFilter
+	// Expiry is how long a host is kept after it was last seen.
+	Expiry time.Duration
+}
+
+// Execute browses the service types and returns the discovered hosts sorted by name. A host missing from a browse is
+// kept until it expires. If some service types fail to browse, the hosts are returned together with the error.
+func (u *DiscoverHostsUseCase) Execute(ctx context.Context, cmd DiscoverHostsCommand) ([]DiscoveredHost, error) {
+	services := make(map[string][]string)
+
+	var errs []error
+
+	for _, service := range cmd.Services {
+		instances, err := u.browser.Browse(ctx, service, cmd.Timeout)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			errs = append(errs, fmt.Errorf("failed to browse %s: %w", service, err))
+
+			continue
+		}
+
+		for _, instance := range instances {
+			host := strings.ToLower(instance.Host)
+			if host == "" || !cmd.Filter.Match(host) {
+				continue
+			}
+
+			services[host] = append(services[host], instance.Name())
+		}
+	}
+
+	hosts := u.update(ctx, services, cmd.Expiry)
+
+	if len(errs) > 0 {
+		return hosts, errors.Join(errs...)
+	}
+
+	return hosts, nil
+}
+
+func (u *DiscoverHostsUseCase) update(
+	ctx context.Context,
+	services map[string][]string,
+	expiry time.Duration,
+) []DiscoveredHost {
+	now := u.now()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for name, instances := range services {
+		host, ok := u.hosts[name]
+		if !ok {
+			host = DiscoveredHost{Name: name, FirstSeen: now}
+
+			u.logger.InfoContext(ctx, "Discovered host",
+				slog.String("host", name),
+				slog.Any("services", instances),
+			)
+		}
+
+		slices.Sort(instances)
+
+		host.Services = slices.Compact(instances)
+		host.LastSeen = now
+
+		u.hosts[name] = host
+	}
+
+	for name, host := range u.hosts {
+		if now.Sub(host.LastSeen) <= expiry {
+			continue
+		}
+
+		u.logger.InfoContext(ctx, "Discovered host expired",
+			slog.String("host", name),
+			slog.Time("last_seen", host.LastSeen),
+		)
+
+		delete(u.hosts, name)
+	}
+
+	hosts := make([]DiscoveredHost, 0, len(u.hosts))
+	for _, name := range slices.Sorted(maps.Keys(u.hosts)) {
+		hosts = append(hosts, u.hosts[name])
+	}
+
+	u.logger.DebugContext(ctx, "Updated discovered hosts",
+		slog.Int("seen", len(services)),
+		slog.Int("hosts", len(hosts)),
+	)
+
+	return hosts
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
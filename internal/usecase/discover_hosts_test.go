@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+	portsm "github.com/khmm12/mdns-health-checker/internal/ports/mocks"
+)
+
+func TestDiscoverHostsUseCase_DiscoversFilteredHosts(t *testing.T) {
+	browser := portsm.NewMockServiceBrowser(t)
+	uc := newTestDiscoverHostsUseCase(browser)
+
+	browser.On("Browse", mock.Anything, "_hap._tcp", 3*time.Second).Return([]ports.ServiceInstance{
+		{Instance: "Plug A", Service: "_hap._tcp.local", Host: "Shelly-Plug-A.local"},
+		{Instance: "Plug B", Service: "_hap._tcp.local", Host: "shelly-plug-b.local"},
+		{Instance: "Bridge", Service: "_hap._tcp.local", Host: "bridge.local"},
+		{Instance: "Unresolved", Service: "_hap._tcp.local"},
+	}, nil)
+	browser.On("Browse", mock.Anything, "_http._tcp", 3*time.Second).Return([]ports.ServiceInstance{
+		{Instance: "Plug A", Service: "_http._tcp.local", Host: "shelly-plug-a.local"},
+	}, nil)
+
+	hosts, err := uc.Execute(t.Context(), DiscoverHostsCommand{
+		Services: []string{"_hap._tcp", "_http._tcp"},
+		Timeout:  3 * time.Second,
+		Filter:   HostFilter{Include: []string{"shelly-*"}, Exclude: []string{"*-b.local"}},
+		Expiry:   time.Hour,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []DiscoveredHost{
+		{
+			Name:      "shelly-plug-a.local",
+			Services:  []string{"Plug A._hap._tcp.local", "Plug A._http._tcp.local"},
+			FirstSeen: testNow,
+			LastSeen:  testNow,
+		},
+	}, hosts)
+}
+
+func TestDiscoverHostsUseCase_ExpiresHostsAfterGracePeriod(t *testing.T) {
+	browser := portsm.NewMockServiceBrowser(t)
+	uc := newTestDiscoverHostsUseCase(browser)
+
+	cmd := DiscoverHostsCommand{Services: []string{"_hap._tcp"}, Timeout: time.Second, Expiry: 10 * time.Minute}
+	plug := ports.ServiceInstance{Instance: "Plug", Service: "_hap._tcp.local", Host: "plug.local"}
+
+	browser.On("Browse", mock.Anything, "_hap._tcp", time.Second).Return([]ports.ServiceInstance{plug}, nil).Once()
+
+	hosts, err := uc.Execute(t.Context(), cmd)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+
+	// A host missing from a browse is kept within the grace period.
+	browser.On("Browse", mock.Anything, "_hap._tcp", time.Second).Return([]ports.ServiceInstance{}, nil)
+
+	uc.now = func() time.Time { return testNow.Add(10 * time.Minute) }
+
+	hosts, err = uc.Execute(t.Context(), cmd)
+	require.NoError(t, err)
+	require.Equal(t, []DiscoveredHost{
+		{Name: "plug.local", Services: []string{"Plug._hap._tcp.local"}, FirstSeen: testNow, LastSeen: testNow},
+	}, hosts)
+
+	uc.now = func() time.Time { return testNow.Add(11 * time.Minute) }
+
+	hosts, err = uc.Execute(t.Context(), cmd)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+}
+
+func TestDiscoverHostsUseCase_KeepsHostsOfFailedBrowse(t *testing.T) {
+	browser := portsm.NewMockServiceBrowser(t)
+	uc := newTestDiscoverHostsUseCase(browser)
+
+	cmd := DiscoverHostsCommand{Services: []string{"_hap._tcp"}, Timeout: time.Second, Expiry: time.Hour}
+
+	browser.On("Browse", mock.Anything, "_hap._tcp", time.Second).Return([]ports.ServiceInstance{
+		{Instance: "Plug", Service: "_hap._tcp.local", Host: "plug.local"},
+	}, nil).Once()
+	browser.On("Browse", mock.Anything, "_hap._tcp", time.Second).Return(nil, errors.New("socket closed"))
+
+	_, err := uc.Execute(t.Context(), cmd)
+	require.NoError(t, err)
+
+	hosts, err := uc.Execute(t.Context(), cmd)
+	require.ErrorContains(t, err, "failed to browse _hap._tcp: socket closed")
+	require.Len(t, hosts, 1)
+}
+
+func TestHostFilter_Match(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter HostFilter
+		host   string
+		want   bool
+	}{
+		{name: "empty filter", host: "plug.local", want: true},
+		{name: "included", filter: HostFilter{Include: []string{"shelly-*"}}, host: "shelly-1.local", want: true},
+		{name: "not included", filter: HostFilter{Include: []string{"shelly-*"}}, host: "plug.local", want: false},
+		{name: "excluded", filter: HostFilter{Exclude: []string{"printer*"}}, host: "printer.local", want: false},
+		{
+			name:   "exclude wins",
+			filter: HostFilter{Include: []string{"*.local"}, Exclude: []string{"tv.local"}},
+			host:   "tv.local",
+			want:   false,
+		},
+		{name: "malformed pattern", filter: HostFilter{Include: []string{"["}}, host: "plug.local", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.filter.Match(tt.host))
+		})
+	}
+}
+
+func newTestDiscoverHostsUseCase(browser ports.ServiceBrowser) *DiscoverHostsUseCase {
+	uc := NewDiscoverHostsUseCase(slog.New(slog.NewTextHandler(io.Discard, nil)), browser)
+	uc.now = func() time.Time { return testNow }
+
+	return uc
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+// TXTExpectation is an assertion on a key of the TXT record a service instance advertises.
+type TXTExpectation struct {
+	Key string
+	// Value must match the whole value of the key. Nil only requires the key to be present.
+	Value *regexp.Regexp
+}
+
+// checkTXT returns the reason code and the description of the first failed expectation.
+func checkTXT(service *ports.ServiceInstance, expectations []TXTExpectation) (string, string) {
+	var txt []string
+	if service != nil {
+		txt = service.Text
+	}
+
+	attrs := parseTXT(txt)
+
+	for _, e := range expectations {
+		key := strings.ToLower(e.Key)
+
+		value, ok := attrs[key]
+		if !ok {
+			return "txt_key_missing:" + key, fmt.Sprintf("key %q is not advertised", e.Key)
+		}
+
+		if e.Value != nil && !e.Value.MatchString(value) {
+			return "txt_value_mismatch:" + key, fmt.Sprintf("key %q is %q, expected to match %q", e.Key, value, e.Value)
+		}
+	}
+
+	return "", ""
+}
+
+// parseTXT parses the key/value pairs of a TXT record (RFC 6763 §6.3).
+func parseTXT(txt []string) map[string]string {
+	attrs := make(map[string]string, len(txt))
+
+	for _, s := range txt {
+		key, value, _ := strings.Cut(s, "=")
+		if key == "" {
+			continue
+		}
+
+		key = strings.ToLower(key)
+		if _, ok := attrs[key]; !ok {
+			attrs[key] = value
+		}
+	}
+
+	return attrs
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/khmm12/mdns-health-checker/internal/ports"
+)
+
+func TestCheckTXT(t *testing.T) {
+	service := &ports.ServiceInstance{Text: []string{"sf=0", "fv=2.1.4", "MD=Bridge", "sf=1", "ci"}}
+
+	tests := []struct {
+		name         string
+		expectations []TXTExpectation
+		reason       string
+	}{
+		{
+			name: "all hold",
+			expectations: []TXTExpectation{
+				{Key: "sf", Value: regexp.MustCompile(`^(?:0)$`)},
+				{Key: "fv", Value: regexp.MustCompile(`^(?:2\..*)$`)},
+				{Key: "md"},
+				{Key: "ci"},
+			},
+		},
+		{
+			name:         "missing key",
+			expectations: []TXTExpectation{{Key: "sf"}, {Key: "id"}},
+			reason:       "txt_key_missing:id",
+		},
+		{
+			name:         "value mismatch",
+			expectations: []TXTExpectation{{Key: "FV", Value: regexp.MustCompile(`^(?:3\..*)$`)}},
+			reason:       "txt_value_mismatch:fv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := checkTXT(service, tt.expectations)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestCheckTXT_WithoutService(t *testing.T) {
+	reason, detail := checkTXT(nil, []TXTExpectation{{Key: "sf"}})
+	require.Equal(t, "txt_key_missing:sf", reason)
+	require.Equal(t, `key "sf" is not advertised`, detail)
+}